@@ -0,0 +1,238 @@
+package skytraq
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Where the device stores a configuration change.
+type Attributes uint8
+
+const (
+	UpdateSRAM         Attributes = 0
+	UpdateSRAMAndFlash Attributes = 1
+)
+
+// Type of messages output by the device.
+type MessageType uint8
+
+const (
+	MessageTypeNone   MessageType = 0
+	MessageTypeNMEA   MessageType = 1
+	MessageTypeBinary MessageType = 2
+)
+
+const (
+	// COM port number of the device's first serial port
+	comPort1 = 0
+
+	// bits on the wire per byte with 8N1 framing
+	bitsPerByte = 10
+
+	// size of a navigation data frame (0xA8) including preamble, length, ID, checksum and end marker
+	binaryEpochSize = 66
+	// longest NMEA sentence, including the end of line, used as the size of every sentence
+	nmeaSentenceSize = 82
+	// GSV sentences output each epoch, enough for the satellites in view of two constellations
+	nmeaGSVSentences = 4
+)
+
+// NMEA sentences assumed to be output by a device until ConfigureNMEA is used
+var defaultNMEAIntervals = NMEAIntervals{GGA: 1, GSA: 1, GSV: 1, RMC: 1, VTG: 1}
+
+// Position update rates, in Hz, supported by the device
+var positionRates = []int{1, 2, 4, 5, 8, 10, 20}
+
+func (mt MessageType) String() string {
+	switch mt {
+	case MessageTypeNone:
+		return "none"
+	case MessageTypeNMEA:
+		return "NMEA"
+	case MessageTypeBinary:
+		return "binary"
+	}
+	return fmt.Sprintf("unknown (%v)", uint8(mt))
+}
+
+// Interval, in position updates, between each NMEA sentence type being output, so 1 outputs the
+// sentence with every update. Zero disables the sentence.
+type NMEAIntervals struct {
+	GGA int
+	GSA int
+	GSV int
+	GLL int
+	RMC int
+	VTG int
+	ZDA int
+}
+
+// Intervals in the order of the configuration message.
+func (n NMEAIntervals) intervals() []int {
+	return []int{n.GGA, n.GSA, n.GSV, n.GLL, n.RMC, n.VTG, n.ZDA}
+}
+
+// Upper bound on the bytes output in an epoch, assuming every enabled sentence is output each epoch.
+func (n NMEAIntervals) epochSize() int {
+	size := 0
+	for _, interval := range n.intervals() {
+		if interval > 0 {
+			size += nmeaSentenceSize
+		}
+	}
+	if n.GSV > 0 {
+		size += (nmeaGSVSentences - 1) * nmeaSentenceSize
+	}
+	return size
+}
+
+func (n NMEAIntervals) data(attr Attributes) ([]byte, error) {
+	data := []byte{}
+	for _, interval := range n.intervals() {
+		if interval < 0 || interval > 255 {
+			return nil, errors.Errorf("NMEA interval %v is outside of the range 0 to 255", interval)
+		}
+		data = append(data, byte(interval))
+	}
+	return append(data, byte(attr)), nil
+}
+
+// Returned when the serial port's baud rate is too low to carry the device's output at the requested
+// position update rate. Only the output of each position update is counted, so raw measurement
+// messages enabled by ConfigureBinaryMeasurement, which are output at their own rate, are not
+// included and need bandwidth beyond Required.
+type BandwidthError struct {
+	Rate        int
+	Baud        int
+	Required    int // baud needed to carry the output at the rate
+	MessageType MessageType
+}
+
+func (e *BandwidthError) Error() string {
+	return fmt.Sprintf("%v output at %v Hz requires %v baud but port is at %v",
+		e.MessageType, e.Rate, e.Required, e.Baud)
+}
+
+// Bytes output by the device each epoch for the connection's message type.
+func (c *Connection) epochSize() int {
+	switch c.messageType {
+	case MessageTypeNMEA:
+		return c.nmeaIntervals.epochSize()
+	case MessageTypeBinary:
+		return binaryEpochSize
+	}
+	return 0
+}
+
+// Send a configuration command and wait for it to be acknowledged.
+func (c *Connection) configure(ctx context.Context, f *Frame) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.WriteFrame(f)
+}
+
+// Change the baud rate of the device's serial port. Once the device acknowledges the change the
+// connection's port is reopened at the new rate.
+func (c *Connection) ConfigureSerialPort(ctx context.Context, baud int, attr Attributes) error {
+	code := -1
+	for i, rate := range baudRates {
+		if rate == baud {
+			code = i
+			break
+		}
+	}
+	if code < 0 {
+		return errors.Errorf("unsupported baud rate %v", baud)
+	}
+
+	if err := c.configure(ctx, &Frame{
+		ID:   CommandConfigureSerialPort,
+		Data: []byte{comPort1, byte(code), byte(attr)},
+	}); err != nil {
+		return errors.Wrapf(err, "unable to configure serial port")
+	}
+
+	logrus.WithField("baud", baud).Info("reopening port at new baud rate")
+	if err := c.Close(); err != nil {
+		logrus.Warnf("error when closing port: %v", err)
+	}
+	c.port = nil
+	c.portConfig.Baud = baud
+	return c.open()
+}
+
+// Set the type of messages output by the device.
+func (c *Connection) SetMessageType(ctx context.Context, mt MessageType, attr Attributes) error {
+	if err := c.configure(ctx, &Frame{
+		ID:   CommandConfigureMessageType,
+		Data: []byte{byte(mt), byte(attr)},
+	}); err != nil {
+		return errors.Wrapf(err, "unable to configure message type")
+	}
+	c.messageType = mt
+	return nil
+}
+
+// Set the intervals at which the device outputs each NMEA sentence type.
+func (c *Connection) ConfigureNMEA(ctx context.Context, intervals NMEAIntervals, attr Attributes) error {
+	data, err := intervals.data(attr)
+	if err != nil {
+		return err
+	}
+	if err := c.configure(ctx, &Frame{
+		ID:   CommandConfigureNMEA,
+		Data: data,
+	}); err != nil {
+		return errors.Wrapf(err, "unable to configure NMEA intervals")
+	}
+	c.nmeaIntervals = intervals
+	return nil
+}
+
+// Set the power mode of the device.
+func (c *Connection) SetPowerMode(ctx context.Context, mode PowerMode, attr Attributes) error {
+	if err := c.configure(ctx, &Frame{
+		ID:   CommandConfigurePowerMode,
+		Data: []byte{byte(mode), byte(attr)},
+	}); err != nil {
+		return errors.Wrapf(err, "unable to configure power mode")
+	}
+	return nil
+}
+
+// Set the rate, in Hz, at which the device updates its position. Rates the current baud rate cannot
+// carry for the connection's message type are refused with a *BandwidthError. Until SetMessageType
+// is used the device is assumed to output NMEA, as devices do on boot, and until ConfigureNMEA is
+// used the common sentences are assumed to be enabled.
+func (c *Connection) SetPositionUpdateRate(ctx context.Context, hz int, attr Attributes) error {
+	supported := false
+	for _, rate := range positionRates {
+		if rate == hz {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return errors.Errorf("unsupported position update rate %v Hz", hz)
+	}
+
+	if required := hz * c.epochSize() * bitsPerByte; required > c.portConfig.Baud {
+		return &BandwidthError{
+			Rate:        hz,
+			Baud:        c.portConfig.Baud,
+			Required:    required,
+			MessageType: c.messageType,
+		}
+	}
+
+	if err := c.configure(ctx, &Frame{
+		ID:   CommandConfigurePositionRate,
+		Data: []byte{byte(hz), byte(attr)},
+	}); err != nil {
+		return errors.Wrapf(err, "unable to configure position update rate")
+	}
+	return nil
+}
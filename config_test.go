@@ -0,0 +1,223 @@
+package skytraq
+
+import (
+	"context"
+	"github.com/jd3nn1s/serial"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfigureSerialPort(t *testing.T) {
+	oldOpenPort := openPort
+	defer func() {
+		openPort = oldOpenPort
+	}()
+	reopened := MockSerialPort{}
+	var reopenedBaud int
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		reopenedBaud = config.Baud
+		return &reopened, nil
+	}
+
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigureSerialPort)}, 0))
+	reopened.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+
+	assert.NoError(t, c.ConfigureSerialPort(context.Background(), 115200, UpdateSRAMAndFlash))
+	assert.Equal(t, frameData(CommandConfigureSerialPort, []byte{0, 5, 1}, 0), m.WriteBuf.Bytes())
+	assert.True(t, m.closed)
+	assert.Equal(t, 115200, reopenedBaud)
+	assert.Equal(t, 115200, c.Baud())
+	assert.Equal(t, frameData(CommandQuerySoftwareVersion, []byte{1}, 0), reopened.WriteBuf.Bytes())
+}
+
+func TestConfigureSerialPortUnsupported(t *testing.T) {
+	c, m := connection()
+	assert.EqualError(t, c.ConfigureSerialPort(context.Background(), 1200, UpdateSRAM),
+		"unsupported baud rate 1200")
+	assert.Equal(t, 0, m.WriteBuf.Len())
+}
+
+func TestConfigureSerialPortNACK(t *testing.T) {
+	c, m := connection()
+	c.writeRetries = 1
+	m.ReadBuf.Write(frameData(ResponseNACK, []byte{byte(CommandConfigureSerialPort)}, 0))
+
+	assert.Error(t, c.ConfigureSerialPort(context.Background(), 115200, UpdateSRAM))
+	assert.False(t, m.closed)
+	assert.Equal(t, defaultBaud, c.Baud())
+}
+
+func TestSetPositionUpdateRate(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigurePositionRate)}, 0))
+
+	assert.NoError(t, c.SetPositionUpdateRate(context.Background(), 10, UpdateSRAM))
+	assert.Equal(t, frameData(CommandConfigurePositionRate, []byte{10, 0}, 0), m.WriteBuf.Bytes())
+}
+
+func TestSetPositionUpdateRateUnsupported(t *testing.T) {
+	c, m := connection()
+	assert.EqualError(t, c.SetPositionUpdateRate(context.Background(), 3, UpdateSRAM),
+		"unsupported position update rate 3 Hz")
+	assert.Equal(t, 0, m.WriteBuf.Len())
+}
+
+func TestSetPositionUpdateRateBandwidth(t *testing.T) {
+	tests := []struct {
+		name        string
+		baud        int
+		messageType MessageType
+		rate        int
+		required    int
+		ok          bool
+	}{
+		{"binary9600At10Hz", 9600, MessageTypeBinary, 10, 0, true},
+		{"binary9600At20Hz", 9600, MessageTypeBinary, 20, 13200, false},
+		{"nmea9600At1Hz", 9600, MessageTypeNMEA, 1, 0, true},
+		{"nmea9600At2Hz", 9600, MessageTypeNMEA, 2, 13120, false},
+		{"nmea230400At20Hz", 230400, MessageTypeNMEA, 20, 0, true},
+		{"nmea115200At20Hz", 115200, MessageTypeNMEA, 20, 131200, false},
+		{"nmea38400At10Hz", 38400, MessageTypeNMEA, 10, 65600, false},
+		{"none4800At20Hz", 4800, MessageTypeNone, 20, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, m := connection()
+			c.portConfig.Baud = tt.baud
+			c.messageType = tt.messageType
+			m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigurePositionRate)}, 0))
+
+			err := c.SetPositionUpdateRate(context.Background(), tt.rate, UpdateSRAM)
+			if tt.ok {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, &BandwidthError{
+				Rate:        tt.rate,
+				Baud:        tt.baud,
+				Required:    tt.required,
+				MessageType: tt.messageType,
+			}, err)
+			assert.Equal(t, 0, m.WriteBuf.Len())
+		})
+	}
+}
+
+func TestBandwidthError(t *testing.T) {
+	err := &BandwidthError{Rate: 20, Baud: 9600, Required: 13200, MessageType: MessageTypeBinary}
+	assert.EqualError(t, err, "binary output at 20 Hz requires 13200 baud but port is at 9600")
+}
+
+func TestSetMessageType(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigureMessageType)}, 0))
+
+	assert.NoError(t, c.SetMessageType(context.Background(), MessageTypeNMEA, UpdateSRAMAndFlash))
+	assert.Equal(t, frameData(CommandConfigureMessageType, []byte{1, 1}, 0), m.WriteBuf.Bytes())
+	assert.Equal(t, MessageTypeNMEA, c.messageType)
+}
+
+func TestSetMessageTypeNACK(t *testing.T) {
+	c, m := connection()
+	c.writeRetries = 1
+	m.ReadBuf.Write(frameData(ResponseNACK, []byte{byte(CommandConfigureMessageType)}, 0))
+
+	assert.Error(t, c.SetMessageType(context.Background(), MessageTypeNone, UpdateSRAM))
+	assert.Equal(t, MessageTypeNMEA, c.messageType)
+}
+
+func TestConnectBinaryOutput(t *testing.T) {
+	oldOpenPort := openPort
+	defer func() {
+		openPort = oldOpenPort
+	}()
+	m := MockSerialPort{}
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		return &m, nil
+	}
+
+	m.ReadBuf.WriteString("$GPGGA,,,,,,0,00,,,M,,M,,*66\r\n")
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+	m.ReadBuf.WriteString("$GPGGA,,,,,,0,00,,,M,,M,,*66\r\n")
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigureMessageType)}, 0))
+
+	c, err := ConnectWithOptions("fakeport", WithBinaryOutput())
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeBinary, c.messageType)
+	assert.Equal(t,
+		append(frameData(CommandQuerySoftwareVersion, []byte{1}, 0),
+			frameData(CommandConfigureMessageType, []byte{2, 0}, 0)...),
+		m.WriteBuf.Bytes())
+}
+
+func TestConfigureNMEA(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals NMEAIntervals
+		attr      Attributes
+		data      []byte
+	}{
+		{"disabled", NMEAIntervals{}, UpdateSRAM, []byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{"ordering", NMEAIntervals{GGA: 1, GSA: 2, GSV: 3, GLL: 4, RMC: 5, VTG: 6, ZDA: 7}, UpdateSRAMAndFlash,
+			[]byte{1, 2, 3, 4, 5, 6, 7, 1}},
+		{"maximum", NMEAIntervals{GGA: 1, GSV: 255, RMC: 1}, UpdateSRAM, []byte{1, 0, 255, 0, 1, 0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, m := connection()
+			m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigureNMEA)}, 0))
+
+			assert.NoError(t, c.ConfigureNMEA(context.Background(), tt.intervals, tt.attr))
+			assert.Equal(t, frameData(CommandConfigureNMEA, tt.data, 0), m.WriteBuf.Bytes())
+		})
+	}
+}
+
+func TestSetPositionUpdateRateDefaultsToNMEA(t *testing.T) {
+	// a device that has not been switched to binary output is checked against NMEA output
+	c, m := connection()
+	c.portConfig.Baud = 9600
+	err := c.SetPositionUpdateRate(context.Background(), 20, UpdateSRAM)
+	assert.IsType(t, &BandwidthError{}, err)
+	assert.Equal(t, MessageTypeNMEA, err.(*BandwidthError).MessageType)
+	assert.Equal(t, 0, m.WriteBuf.Len())
+
+	// fewer sentences allow a higher rate
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigureNMEA)}, 0))
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigurePositionRate)}, 0))
+	assert.NoError(t, c.ConfigureNMEA(context.Background(), NMEAIntervals{RMC: 1}, UpdateSRAM))
+	assert.NoError(t, c.SetPositionUpdateRate(context.Background(), 10, UpdateSRAM))
+}
+
+func TestNMEAIntervalsEpochSize(t *testing.T) {
+	assert.Equal(t, 0, NMEAIntervals{}.epochSize())
+	assert.Equal(t, 82, NMEAIntervals{GGA: 5}.epochSize())
+	assert.Equal(t, 656, defaultNMEAIntervals.epochSize())
+}
+
+func TestConfigureNMEAOutOfRange(t *testing.T) {
+	c, m := connection()
+	assert.EqualError(t, c.ConfigureNMEA(context.Background(), NMEAIntervals{GSV: 256}, UpdateSRAM),
+		"NMEA interval 256 is outside of the range 0 to 255")
+	assert.Equal(t, 0, m.WriteBuf.Len())
+}
+
+func TestSetPowerMode(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigurePowerMode)}, 0))
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQueryPowerMode)}, 0))
+	m.ReadBuf.Write(frameData(ResponsePowerMode, []byte{1}, 0))
+
+	assert.NoError(t, c.SetPowerMode(context.Background(), PowerSave, UpdateSRAMAndFlash))
+	mode, err := c.QueryPowerMode(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, PowerSave, mode)
+	assert.Equal(t, "power save", mode.String())
+	assert.Equal(t,
+		append(frameData(CommandConfigurePowerMode, []byte{1, 1}, 0),
+			frameData(CommandQueryPowerMode, []byte{}, 0)...),
+		m.WriteBuf.Bytes())
+}
@@ -1,7 +1,9 @@
 package skytraq
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"github.com/jd3nn1s/serial"
 	"github.com/pkg/errors"
@@ -13,15 +15,23 @@ import (
 const (
 	DataMaxSize   = 65535
 	EndMarkerSize = 3
-	baud          = 230400
 
-	maxIncorrectMessageIDCount = 5
-)
+	defaultBaud                       = 230400
+	defaultReadTimeout                = 10 * time.Second
+	defaultWriteRetries               = 3
+	defaultMaxIncorrectMessageIDCount = 5
 
-var (
-	maxWriteRetries = 3
+	// read timeout while probing for the device's baud rate, short as most rates will see no reply
+	baudProbeTimeout = 500 * time.Millisecond
+	// longest a probe may take, as a device sending at another rate produces a constant stream of
+	// garbage that never times out
+	baudProbeDuration = 2 * time.Second
 )
 
+// Baud rates supported by the device. The index of each rate is its code in the serial port
+// configuration message.
+var baudRates = []int{4800, 9600, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
 // All binary protocol data is big endian
 
 type SerialPort interface {
@@ -32,6 +42,29 @@ type SerialPort interface {
 type Connection struct {
 	portConfig *serial.Config
 	port       SerialPort
+	reader     *bufio.Reader
+
+	// replaces openPort when set, e.g. for a replayed capture
+	portOpener func(config *serial.Config) (SerialPort, error)
+
+	writeRetries               int
+	maxIncorrectMessageIDCount int
+	detectBaud                 bool
+	binaryOutput               bool
+	ephemerisCache             *EphemerisCache
+	setEphemerisID             MessageID
+	recorder                   *Recorder
+
+	// output the device is assumed to be sending, NMEA until set as devices boot outputting NMEA
+	messageType   MessageType
+	nmeaIntervals NMEAIntervals
+
+	// leap seconds reported by the device, replacing DefaultLeapSeconds when valid
+	leapSeconds      int
+	leapSecondsValid bool
+
+	// satellites in view from NMEA sentences
+	satellites satelliteTracker
 
 	// max data size + checksum + end of sequence marker
 	buf [DataMaxSize + EndMarkerSize]byte
@@ -42,24 +75,220 @@ var openPort = func(config *serial.Config) (SerialPort, error) {
 	return serial.OpenPort(config)
 }
 
+// Configures a connection before it is opened.
+type Option func(*Connection)
+
+// Baud rate of the serial port. Defaults to 230400.
+func WithBaud(baud int) Option {
+	return func(c *Connection) {
+		c.portConfig.Baud = baud
+	}
+}
+
+// Time to wait for data on the serial port before a read fails. Defaults to 10 seconds.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(c *Connection) {
+		c.portConfig.ReadTimeout = timeout
+	}
+}
+
+// Number of times a frame is sent before WriteFrame gives up waiting for an ACK. Must be at least 1
+// and defaults to 3.
+func WithWriteRetries(retries int) Option {
+	return func(c *Connection) {
+		c.writeRetries = retries
+	}
+}
+
+// Number of unrelated frames that may be received while waiting for an ACK or NACK before the wait
+// fails. Must be at least 1 and defaults to 5.
+func WithMaxIrrelevantFrames(count int) Option {
+	return func(c *Connection) {
+		c.maxIncorrectMessageIDCount = count
+	}
+}
+
+// Probe for the device's baud rate when connecting, starting with the configured rate and then
+// trying each supported rate from 4800 to 921600. The detected rate is available from Baud.
+func WithBaudDetection() Option {
+	return func(c *Connection) {
+		c.detectBaud = true
+	}
+}
+
+// Switch the device to binary output when connecting, as devices will often start up outputting
+// NMEA. The change is made in SRAM only.
+func WithBinaryOutput() Option {
+	return func(c *Connection) {
+		c.binaryOutput = true
+	}
+}
+
+// Upload ephemeris from the cache when connecting. Failing to restore ephemeris is logged and does
+// not prevent the connection being made.
+func WithEphemerisCache(cache *EphemerisCache) Option {
+	return func(c *Connection) {
+		c.ephemerisCache = cache
+	}
+}
+
+// Message ID SetEphemeris uploads with. Venus 8 devices use CommandSetEphemeris, the default, and
+// Venus 6 devices use CommandSetEphemerisVenus6.
+func WithSetEphemerisCommand(id MessageID) Option {
+	return func(c *Connection) {
+		c.setEphemerisID = id
+	}
+}
+
+// Record the bytes read from and written to the port once connected. See SetRecorder.
+func WithRecorder(r *Recorder) Option {
+	return func(c *Connection) {
+		c.recorder = r
+	}
+}
+
+func newConnection(portName string) *Connection {
+	return &Connection{
+		portConfig: &serial.Config{
+			Name:        portName,
+			Baud:        defaultBaud,
+			ReadTimeout: defaultReadTimeout,
+		},
+		writeRetries:               defaultWriteRetries,
+		maxIncorrectMessageIDCount: defaultMaxIncorrectMessageIDCount,
+		setEphemerisID:             CommandSetEphemeris,
+		messageType:                MessageTypeNMEA,
+		nmeaIntervals:              defaultNMEAIntervals,
+	}
+}
+
+// Connect to a device using the default serial port settings.
 func Connect(portName string) (*Connection, error) {
-	c := &serial.Config{
-		Name:        portName,
-		Baud:        baud,
-		ReadTimeout: 10 * time.Second,
+	return ConnectWithOptions(portName)
+}
+
+// Connect to a device with serial port settings and retry limits configured by opts.
+func ConnectWithOptions(portName string, opts ...Option) (*Connection, error) {
+	conn := newConnection(portName)
+	for _, opt := range opts {
+		opt(conn)
+	}
+	if conn.writeRetries < 1 {
+		return conn, errors.Errorf("write retries must be at least 1 but is %v", conn.writeRetries)
+	}
+	if conn.maxIncorrectMessageIDCount < 1 {
+		return conn, errors.Errorf("max irrelevant frames must be at least 1 but is %v",
+			conn.maxIncorrectMessageIDCount)
+	}
+	if conn.setEphemerisID != CommandSetEphemeris && conn.setEphemerisID != CommandSetEphemerisVenus6 {
+		return conn, errors.Errorf("unsupported set ephemeris message ID %#x", uint8(conn.setEphemerisID))
 	}
 
-	conn := Connection{
-		portConfig: c,
+	if conn.detectBaud {
+		if err := conn.detectBaudRate(); err != nil {
+			return conn, err
+		}
+	}
+	if err := conn.open(); err != nil {
+		return conn, err
+	}
+	if conn.binaryOutput {
+		if err := conn.SetMessageType(context.Background(), MessageTypeBinary, UpdateSRAM); err != nil {
+			return conn, err
+		}
 	}
+	if conn.ephemerisCache != nil {
+		if err := conn.RestoreEphemeris(context.Background(), conn.ephemerisCache); err != nil {
+			logrus.Warnf("unable to restore ephemeris: %v", err)
+		}
+	}
+	return conn, nil
+}
 
-	err := conn.open()
-	return &conn, err
+// Baud rate of the serial port, which is the detected rate if baud rate detection was used.
+func (c *Connection) Baud() int {
+	return c.portConfig.Baud
+}
+
+// Find the baud rate the device is using by sending a software version query at each rate until
+// one is acknowledged. The port config is updated with the rate found.
+func (c *Connection) detectBaudRate() error {
+	// probes are not recorded, as data read at the wrong rate would replay as garbage
+	recorder := c.recorder
+	c.recorder = nil
+	defer func() {
+		c.recorder = recorder
+	}()
+
+	rates := []int{c.portConfig.Baud}
+	for _, rate := range baudRates {
+		if rate != c.portConfig.Baud {
+			rates = append(rates, rate)
+		}
+	}
+
+	for _, rate := range rates {
+		err := c.probeBaudRate(rate)
+		if err == nil {
+			logrus.WithField("baud", rate).Info("detected baud rate")
+			c.portConfig.Baud = rate
+			return nil
+		}
+		logrus.WithField("baud", rate).Debugf("baud rate probe failed: %v", err)
+	}
+	return errors.New("unable to detect baud rate")
+}
+
+func (c *Connection) probeBaudRate(baud int) error {
+	config := *c.portConfig
+	config.Baud = baud
+	config.ReadTimeout = baudProbeTimeout
+
+	port, err := openPort(&config)
+	if err != nil {
+		return err
+	}
+	c.port = &probePort{SerialPort: port, deadline: timeNow().Add(baudProbeDuration)}
+	c.reader = nil
+	defer func() {
+		port.Close()
+		c.port = nil
+		c.reader = nil
+	}()
+
+	if err := port.Flush(); err != nil {
+		return err
+	}
+	if err := c.writeFrame(&Frame{
+		ID:   CommandQuerySoftwareVersion,
+		Data: []byte{softwareTypeSystemCode},
+	}); err != nil {
+		return err
+	}
+	return c.readACK(CommandQuerySoftwareVersion)
+}
+
+// A port that fails reads once a baud rate probe has taken too long.
+type probePort struct {
+	SerialPort
+	deadline time.Time
+}
+
+func (p *probePort) Read(buf []byte) (int, error) {
+	if timeNow().After(p.deadline) {
+		return 0, errors.New("baud rate probe timed out")
+	}
+	return p.SerialPort.Read(buf)
 }
 
 func (c *Connection) open() error {
 	var err error
-	c.port, err = openPort(c.portConfig)
+	c.reader = nil
+	opener := openPort
+	if c.portOpener != nil {
+		opener = c.portOpener
+	}
+	c.port, err = opener(c.portConfig)
 	if err != nil {
 		c.port = nil
 		return err
@@ -83,6 +312,40 @@ func (c *Connection) Close() error {
 	return nil
 }
 
+// Record the bytes read from and written to the port from now on, or stop recording if r is nil. The
+// recorder is not closed when the connection is.
+func (c *Connection) SetRecorder(r *Recorder) {
+	c.recorder = r
+}
+
+func (c *Connection) record(d Direction, data []byte) {
+	if c.recorder != nil {
+		c.recorder.Record(d, data)
+	}
+}
+
+// Buffered reader for the open port, created on first use after the port is opened.
+func (c *Connection) input() *bufio.Reader {
+	if c.reader == nil {
+		c.reader = bufio.NewReader(portReader{c})
+	}
+	return c.reader
+}
+
+// Reads from the connection's port, recording the bytes as they are read so that a capture holds
+// exactly what the device sent, including data that is later skipped.
+type portReader struct {
+	c *Connection
+}
+
+func (pr portReader) Read(buf []byte) (int, error) {
+	n, err := pr.c.port.Read(buf)
+	if n > 0 {
+		pr.c.record(DirectionRead, buf[:n])
+	}
+	return n, err
+}
+
 // A canonical read from a serial port reads a complete "line" from the port. A line is not always
 // the requested size and therefore this function will perform additional reads until the supplied
 // buffer is full.
@@ -92,7 +355,7 @@ func (c *Connection) readBytes(buf []byte) error {
 	for {
 		readingSize := targetSize - startPos
 		logrus.Debugf("reading buffer size: %v", readingSize)
-		if n, err := c.port.Read(buf[startPos:targetSize]); err != nil {
+		if n, err := c.input().Read(buf[startPos:targetSize]); err != nil {
 			return errors.Wrapf(err, "unable to read data and end of frame")
 		} else {
 			if n == 0 {
@@ -110,45 +373,75 @@ func (c *Connection) readBytes(buf []byte) error {
 
 // Read a Skytraq frame from the open connection. As devices can be continuously sending data it is
 // possible that an incomplete frame could be received. This data is ignored and the read will still
-// succeed.
+// succeed. NMEA sentences received before the frame are also ignored.
 func (c *Connection) ReadFrame() (*Frame, error) {
-	// pre-amble and length of data
-	var startBuf [4]byte
+	for {
+		f, _, err := c.readMessage()
+		if err != nil || f != nil {
+			return f, err
+		}
+	}
+}
 
-PreambleFind:
+// Read the next binary frame or NMEA sentence from the open connection, whichever arrives first.
+// Exactly one of the frame or sentence is returned when there is no error. Data that is neither,
+// including NMEA sentences with an invalid checksum, is skipped.
+func (c *Connection) readMessage() (*Frame, *Sentence, error) {
+	rd := c.input()
+	skipped := 0
 	for {
-		if err := c.readBytes(startBuf[:]); err != nil {
-			return nil, errors.Wrapf(err, "unable to read start of frame")
+		b, err := rd.ReadByte()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to read start of frame")
 		}
 
-		preamblePos := 0
-		for {
-			for ; startBuf[preamblePos] != 0xa0; preamblePos++ {
-				if preamblePos == 3 {
-					continue PreambleFind
-				}
+		switch b {
+		case 0xa0:
+			next, err := rd.Peek(1)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "unable to read start of frame")
 			}
-			if preamblePos > 0 {
-				logrus.WithField("offset", preamblePos).Info("misaligned data received")
-				destPos := 0
-				for ; preamblePos+destPos < 4; destPos++ {
-					startBuf[destPos] = startBuf[preamblePos+destPos]
-				}
-				c.readBytes(startBuf[destPos:])
-				preamblePos = 0
+			if next[0] != 0xa1 {
+				skipped++
+				continue
 			}
-
-			if startBuf[1] != 0xa1 {
-				// check rest of existing startBuf
-				preamblePos++
+			rd.ReadByte()
+			logSkipped(skipped)
+			f, err := c.readFrameBody()
+			return f, nil, err
+		case '$':
+			s, err := c.readSentence()
+			if err != nil {
+				logrus.Warnf("ignoring NMEA sentence: %v", err)
+				skipped++
 				continue
 			}
-			break PreambleFind
+			logSkipped(skipped)
+			return nil, s, nil
+		default:
+			skipped++
 		}
 	}
+}
+
+func logSkipped(skipped int) {
+	if skipped > 0 {
+		logrus.WithField("offset", skipped).Info("misaligned data received")
+	}
+}
 
-	size := int(binary.BigEndian.Uint16(startBuf[2:4]))
+// Read the remainder of a frame following the pre-amble.
+func (c *Connection) readFrameBody() (*Frame, error) {
+	var sizeBuf [2]byte
+	if err := c.readBytes(sizeBuf[:]); err != nil {
+		return nil, errors.Wrapf(err, "unable to read start of frame")
+	}
+	size := int(binary.BigEndian.Uint16(sizeBuf[:]))
 	logrus.WithField("payloadSize", size).Debug()
+	if size == 0 {
+		return nil, errors.New("frame has no message ID")
+	}
+
 	tmpBuf := c.buf[:size+EndMarkerSize]
 	if err := c.readBytes(tmpBuf); err != nil {
 		return nil, errors.Wrapf(err, "unable to read data and end of frame")
@@ -171,6 +464,32 @@ PreambleFind:
 	return f, nil
 }
 
+// Read the remainder of an NMEA sentence following the '$'. Reading stops without consuming the
+// byte if anything other than printable ASCII is found before the end of the sentence, so that
+// binary frames following a truncated sentence are not lost.
+func (c *Connection) readSentence() (*Sentence, error) {
+	rd := c.input()
+	line := make([]byte, 0, maxSentenceLength)
+	for len(line) < maxSentenceLength {
+		b, err := rd.ReadByte()
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read NMEA sentence")
+		}
+		switch {
+		case b == '\r':
+			if next, err := rd.Peek(1); err == nil && next[0] == '\n' {
+				rd.ReadByte()
+			}
+			return parseSentence(string(line))
+		case b < 0x20 || b > 0x7e:
+			rd.UnreadByte()
+			return nil, errors.Errorf("unexpected byte %#x in NMEA sentence", b)
+		}
+		line = append(line, b)
+	}
+	return nil, errors.New("NMEA sentence too long")
+}
+
 func (c *Connection) writeFrame(f *Frame) error {
 	lenPayload := len(f.Data) + 1 // includes ID
 	startSendBuf := [5]byte{
@@ -205,6 +524,9 @@ func (c *Connection) writeFrame(f *Frame) error {
 
 func (c *Connection) writeBytes(buf []byte) error {
 	s, err := c.port.Write(buf)
+	if s > 0 {
+		c.record(DirectionWrite, buf[:s])
+	}
 	if s != len(buf) || err != nil {
 		if err != nil {
 			return err
@@ -221,7 +543,7 @@ func (c *Connection) writeBytes(buf []byte) error {
 // many non-ACK/NACK frames are received an error is returned.
 func (c *Connection) WriteFrame(f *Frame) error {
 	var err error
-	retries := maxWriteRetries
+	retries := c.writeRetries
 
 	for ; retries > 0; retries-- {
 		if err != nil {
@@ -239,8 +561,8 @@ func (c *Connection) WriteFrame(f *Frame) error {
 
 	if retries == 0 {
 		return errors.Wrapf(err, "exceeded retries")
-	} else if retries < maxWriteRetries {
-		logrus.WithField("retryCount", maxWriteRetries-retries).Warn("write frame successful after retry")
+	} else if retries < c.writeRetries {
+		logrus.WithField("retryCount", c.writeRetries-retries).Warn("write frame successful after retry")
 	}
 	return nil
 }
@@ -274,7 +596,7 @@ func (c *Connection) readACK(id MessageID) error {
 			logrus.WithField("messageID", respFrame.ID).Warn("ignoring non-ACK/NACK frame")
 			irrelevantFrameCount++
 		}
-		if irrelevantFrameCount > maxIncorrectMessageIDCount {
+		if irrelevantFrameCount > c.maxIncorrectMessageIDCount {
 			return errors.Errorf("too many irrelevant messages while waiting for ACK/NACK for message ID %v", id)
 		}
 	}
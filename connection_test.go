@@ -7,6 +7,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 type MockSerialPort struct {
@@ -23,7 +24,7 @@ var navData []byte
 func init() {
 	buf := bytes.Buffer{}
 
-	buf.Write([]byte{byte(Fix3D), 0, 3, 0, 0, 0, 0, 0})
+	buf.Write([]byte{byte(Fix3D), 3, 0, 0, 0, 0, 0, 0})
 	binary.Write(&buf, binary.BigEndian, uint32(1))
 	binary.Write(&buf, binary.BigEndian, uint32(2))
 	binary.Write(&buf, binary.BigEndian, uint32(0))
@@ -80,9 +81,9 @@ func addACK(m *MockSerialPort, id MessageID) {
 
 func connection() (*Connection, *MockSerialPort) {
 	m := MockSerialPort{}
-	return &Connection{
-		port: &m,
-	}, &m
+	c := newConnection("fakeport")
+	c.port = &m
+	return c, &m
 }
 
 func TestWriteBytes(t *testing.T) {
@@ -253,7 +254,7 @@ func TestReadACKWrongType(t *testing.T) {
 func TestReadACKMaxWrongType(t *testing.T) {
 	c, m := connection()
 
-	for i := 0; i < maxIncorrectMessageIDCount+1; i++ {
+	for i := 0; i < defaultMaxIncorrectMessageIDCount+1; i++ {
 		m.ReadBuf.Write(frameData(ResponseNavData, []byte{2}, 0))
 	}
 	m.ReadBuf.Write(frameData(ResponseACK, []byte{3}, 0))
@@ -302,11 +303,7 @@ func TestInternalWriteFrameFail(t *testing.T) {
 
 func TestWriteFrame(t *testing.T) {
 	c, m := connection()
-	oldMaxWriteRetries := maxWriteRetries
-	defer func() {
-		maxWriteRetries = oldMaxWriteRetries
-	}()
-	maxWriteRetries = 1
+	c.writeRetries = 1
 
 	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
 	assert.NoError(t, c.WriteFrame(&Frame{
@@ -317,12 +314,7 @@ func TestWriteFrame(t *testing.T) {
 
 func TestWriteFrameNoAck(t *testing.T) {
 	c, _ := connection()
-	oldMaxWriteRetries := maxWriteRetries
-	defer func() {
-		maxWriteRetries = oldMaxWriteRetries
-	}()
-
-	maxWriteRetries = 1
+	c.writeRetries = 1
 	assert.Error(t, c.WriteFrame(&Frame{
 		ID:   CommandQuerySoftwareVersion,
 		Data: []byte{1},
@@ -331,11 +323,7 @@ func TestWriteFrameNoAck(t *testing.T) {
 
 func TestWriteFrameNack(t *testing.T) {
 	c, m := connection()
-	oldMaxWriteRetries := maxWriteRetries
-	defer func() {
-		maxWriteRetries = oldMaxWriteRetries
-	}()
-	maxWriteRetries = 1
+	c.writeRetries = 1
 
 	m.ReadBuf.Write(frameData(ResponseNACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
 	assert.Error(t, c.WriteFrame(&Frame{
@@ -375,3 +363,115 @@ func TestConnectClose(t *testing.T) {
 	assert.NoError(t, c.Close())
 	assert.Error(t, c.Close())
 }
+
+func TestConnectWithOptions(t *testing.T) {
+	oldOpenPort := openPort
+	defer func() {
+		openPort = oldOpenPort
+	}()
+	m := MockSerialPort{}
+	var portConfig serial.Config
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		portConfig = *config
+		return &m, nil
+	}
+
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+	c, err := ConnectWithOptions("fakeport",
+		WithBaud(9600),
+		WithReadTimeout(time.Second),
+		WithWriteRetries(1),
+		WithMaxIrrelevantFrames(2))
+	assert.NoError(t, err)
+	assert.Equal(t, "fakeport", portConfig.Name)
+	assert.Equal(t, 9600, portConfig.Baud)
+	assert.Equal(t, time.Second, portConfig.ReadTimeout)
+	assert.Equal(t, 1, c.writeRetries)
+	assert.Equal(t, 2, c.maxIncorrectMessageIDCount)
+}
+
+func TestConnectWithInvalidOptions(t *testing.T) {
+	oldOpenPort := openPort
+	defer func() {
+		openPort = oldOpenPort
+	}()
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		t.Fail()
+		return &MockSerialPort{}, nil
+	}
+
+	_, err := ConnectWithOptions("fakeport", WithWriteRetries(0))
+	assert.EqualError(t, err, "write retries must be at least 1 but is 0")
+	_, err = ConnectWithOptions("fakeport", WithMaxIrrelevantFrames(-1))
+	assert.EqualError(t, err, "max irrelevant frames must be at least 1 but is -1")
+	_, err = ConnectWithOptions("fakeport", WithSetEphemerisCommand(CommandGetEphermeris))
+	assert.EqualError(t, err, "unsupported set ephemeris message ID 0x30")
+}
+
+func TestReadACKConfiguredMaxWrongType(t *testing.T) {
+	c, m := connection()
+	c.maxIncorrectMessageIDCount = 1
+
+	m.ReadBuf.Write(frameData(ResponseNavData, []byte{2}, 0))
+	m.ReadBuf.Write(frameData(ResponseNavData, []byte{2}, 0))
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{3}, 0))
+	assert.EqualError(t, c.readACK(3),
+		"too many irrelevant messages while waiting for ACK/NACK for message ID 3")
+}
+
+func TestConnectBaudDetection(t *testing.T) {
+	oldOpenPort := openPort
+	defer func() {
+		openPort = oldOpenPort
+	}()
+	var probed []int
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		probed = append(probed, config.Baud)
+		m := MockSerialPort{}
+		if config.Baud == 115200 {
+			m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+		}
+		return &m, nil
+	}
+
+	c, err := ConnectWithOptions("fakeport", WithBaud(9600), WithBaudDetection())
+	assert.NoError(t, err)
+	assert.Equal(t, 115200, c.Baud())
+	assert.Equal(t, []int{9600, 4800, 19200, 38400, 57600, 115200, 115200}, probed)
+	assert.NoError(t, c.Close())
+}
+
+func TestConnectBaudDetectionFail(t *testing.T) {
+	oldOpenPort := openPort
+	defer func() {
+		openPort = oldOpenPort
+	}()
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		return &MockSerialPort{}, nil
+	}
+
+	_, err := ConnectWithOptions("fakeport", WithBaudDetection())
+	assert.EqualError(t, err, "unable to detect baud rate")
+}
+
+func TestProbeBaudRateStreamingGarbage(t *testing.T) {
+	oldOpenPort, oldTimeNow := openPort, timeNow
+	defer func() {
+		openPort, timeNow = oldOpenPort, oldTimeNow
+	}()
+	m := MockSerialPort{readLimit: 16}
+	m.ReadBuf.Write(bytes.Repeat([]byte{0x55}, 1<<20))
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		return &m, nil
+	}
+	now := time.Now()
+	timeNow = func() time.Time {
+		now = now.Add(10 * time.Millisecond)
+		return now
+	}
+
+	c := newConnection("fakeport")
+	err := c.probeBaudRate(9600)
+	assert.EqualError(t, errors.Cause(err), "baud rate probe timed out")
+	assert.True(t, m.ReadBuf.Len() > 1<<19)
+}
@@ -0,0 +1,117 @@
+package skytraq
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// a reserved byte followed by words 2 to 10 of the subframe
+	ephemerisSubframeSize = 28
+	// SV ID and three subframes, which with the message ID is the 87 byte payload of 0xB1 and 0x41
+	ephemerisDataSize = 2 + 3*ephemerisSubframeSize
+
+	maxGPSSVID = 32
+)
+
+// GPS ephemeris for a single satellite as stored by the device: subframes 1 to 3 of the navigation
+// message. Each subframe is a reserved byte followed by words 2 to 10, three bytes each with parity
+// removed.
+type Ephemeris struct {
+	SVID      int
+	Subframes [3][ephemerisSubframeSize]byte
+}
+
+// Whether the device holds ephemeris for the satellite. The device reports satellites without
+// ephemeris with empty subframes.
+func (e Ephemeris) Valid() bool {
+	for _, subframe := range e.Subframes {
+		for _, b := range subframe[1:] {
+			if b != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e Ephemeris) data() []byte {
+	data := make([]byte, ephemerisDataSize)
+	binary.BigEndian.PutUint16(data[0:2], uint16(e.SVID))
+	for i, subframe := range e.Subframes {
+		copy(data[2+i*ephemerisSubframeSize:], subframe[:])
+	}
+	return data
+}
+
+func (f *Frame) ephemeris() (Ephemeris, error) {
+	const expectedLen = ephemerisDataSize
+	if len(f.Data) != expectedLen {
+		logrus.WithField("length", len(f.Data)).
+			WithField("expectedLen", expectedLen).Error("expecting more data")
+		return Ephemeris{}, errors.Errorf("ephemeris conversion requires %v bytes but received %v",
+			expectedLen, len(f.Data))
+	}
+
+	e := Ephemeris{
+		SVID: int(binary.BigEndian.Uint16(f.Data[0:2])),
+	}
+	for i := range e.Subframes {
+		copy(e.Subframes[i][:], f.Data[2+i*ephemerisSubframeSize:])
+	}
+	return e, nil
+}
+
+// Download GPS ephemeris from the device. When svID is 0 the device sends ephemeris for every
+// satellite and only those it holds ephemeris for are returned.
+func (c *Connection) GetEphemeris(ctx context.Context, svID int) ([]Ephemeris, error) {
+	if svID < 0 || svID > maxGPSSVID {
+		return nil, errors.Errorf("invalid SV ID %v", svID)
+	}
+
+	isEphemeris := func(f *Frame) bool {
+		return f.ID == ResponseEphemerisData
+	}
+	f, err := c.queryFunc(ctx, &Frame{
+		ID:   CommandGetEphermeris,
+		Data: []byte{byte(svID)},
+	}, isEphemeris)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Ephemeris{}
+	for {
+		e, err := f.ephemeris()
+		if err != nil {
+			return nil, err
+		}
+		if e.Valid() {
+			result = append(result, e)
+		}
+		if svID != 0 || e.SVID >= maxGPSSVID {
+			return result, nil
+		}
+
+		if f, err = c.readResponse(ctx, isEphemeris); err != nil {
+			return nil, errors.Wrapf(err, "error when reading ephemeris after SV %v", e.SVID)
+		}
+	}
+}
+
+// Upload GPS ephemeris for a satellite to the device, using the message ID set by
+// WithSetEphemerisCommand.
+func (c *Connection) SetEphemeris(ctx context.Context, eph Ephemeris) error {
+	if eph.SVID < 1 || eph.SVID > maxGPSSVID {
+		return errors.Errorf("invalid SV ID %v", eph.SVID)
+	}
+	if err := c.configure(ctx, &Frame{
+		ID:   c.setEphemerisID,
+		Data: eph.data(),
+	}); err != nil {
+		return errors.Wrapf(err, "unable to set ephemeris for SV %v", eph.SVID)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package skytraq
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func testEphemeris(svID int) Ephemeris {
+	e := Ephemeris{SVID: svID}
+	for i := range e.Subframes {
+		for j := range e.Subframes[i] {
+			e.Subframes[i][j] = byte(svID + i*ephemerisSubframeSize + j)
+		}
+	}
+	return e
+}
+
+// A complete 0xB1 frame for SV 5 laid out as in the binary protocol specification: an 87 byte
+// payload of the message ID, a two byte SV ID and, for each subframe, a reserved byte followed by
+// the 27 bytes of words 2 to 10.
+var ephemerisFrame = []byte{
+	0xa0, 0xa1, 0x00, 0x57, 0xb1,
+	0x00, 0x05,
+	0x00, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e,
+	0x1f, 0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28, 0x29, 0x2a,
+	0x00, 0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28, 0x29, 0x2a, 0x2b, 0x2c, 0x2d, 0x2e,
+	0x2f, 0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0x3a,
+	0x00, 0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0x3a, 0x3b, 0x3c, 0x3d, 0x3e,
+	0x3f, 0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0x4a,
+	0xef, 0x0d, 0x0a,
+}
+
+func TestEphemerisFrame(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(ephemerisFrame)
+	f, err := c.ReadFrame()
+	assert.NoError(t, err)
+
+	e, err := f.ephemeris()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, e.SVID)
+	for i, subframe := range e.Subframes {
+		assert.Equal(t, byte(0), subframe[0])
+		assert.Equal(t, byte(0x10*(i+1)), subframe[1], "word 2 of subframe %v", i+1)
+		assert.Equal(t, byte(0x10*(i+1)+26), subframe[27], "word 10 of subframe %v", i+1)
+	}
+	assert.True(t, e.Valid())
+
+	// uploading sends the same payload
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandSetEphemeris)}, 0))
+	assert.NoError(t, c.SetEphemeris(context.Background(), e))
+	expected := append([]byte{}, ephemerisFrame...)
+	expected[4] = byte(CommandSetEphemeris)
+	expected[len(expected)-3] ^= byte(ResponseEphemerisData ^ CommandSetEphemeris)
+	assert.Equal(t, expected, m.WriteBuf.Bytes())
+}
+
+func TestEphemerisData(t *testing.T) {
+	e := testEphemeris(5)
+	data := e.data()
+	assert.Equal(t, 86, len(data))
+	assert.Equal(t, []byte{0, 5, 5, 6}, data[:4])
+	assert.Equal(t, byte(5+2*ephemerisSubframeSize+27), data[85])
+
+	f := Frame{ID: ResponseEphemerisData, Data: data}
+	decoded, err := f.ephemeris()
+	assert.NoError(t, err)
+	assert.Equal(t, e, decoded)
+	assert.True(t, decoded.Valid())
+	assert.False(t, Ephemeris{SVID: 5}.Valid())
+}
+
+func TestGetEphemeris(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandGetEphermeris)}, 0))
+	m.ReadBuf.Write(frameData(ResponseNavData, navData, 0))
+	m.ReadBuf.Write(frameData(ResponseEphemerisData, testEphemeris(7).data(), 0))
+
+	eph, err := c.GetEphemeris(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []Ephemeris{testEphemeris(7)}, eph)
+	assert.Equal(t, frameData(CommandGetEphermeris, []byte{7}, 0), m.WriteBuf.Bytes())
+}
+
+func TestGetEphemerisAll(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandGetEphermeris)}, 0))
+	var expected []Ephemeris
+	for sv := 1; sv <= maxGPSSVID; sv++ {
+		e := Ephemeris{SVID: sv}
+		if sv%3 == 0 {
+			e = testEphemeris(sv)
+			expected = append(expected, e)
+		}
+		m.ReadBuf.Write(frameData(ResponseEphemerisData, e.data(), 0))
+		if sv == 10 {
+			m.ReadBuf.Write(frameData(ResponseNavData, navData, 0))
+		}
+	}
+
+	eph, err := c.GetEphemeris(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, eph)
+}
+
+func TestGetEphemerisTruncated(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandGetEphermeris)}, 0))
+	m.ReadBuf.Write(frameData(ResponseEphemerisData, testEphemeris(1).data(), 0))
+
+	_, err := c.GetEphemeris(context.Background(), 0)
+	assert.Error(t, err)
+}
+
+func TestGetEphemerisInvalidSV(t *testing.T) {
+	c, _ := connection()
+	_, err := c.GetEphemeris(context.Background(), 33)
+	assert.EqualError(t, err, "invalid SV ID 33")
+}
+
+func TestSetEphemeris(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandSetEphemeris)}, 0))
+
+	assert.NoError(t, c.SetEphemeris(context.Background(), testEphemeris(12)))
+	assert.Equal(t, frameData(CommandSetEphemeris, testEphemeris(12).data(), 0), m.WriteBuf.Bytes())
+
+	assert.EqualError(t, c.SetEphemeris(context.Background(), Ephemeris{}), "invalid SV ID 0")
+}
+
+func TestSetEphemerisVenus6(t *testing.T) {
+	c, m := connection()
+	WithSetEphemerisCommand(CommandSetEphemerisVenus6)(c)
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandSetEphemerisVenus6)}, 0))
+
+	assert.NoError(t, c.SetEphemeris(context.Background(), testEphemeris(12)))
+	assert.Equal(t, frameData(CommandSetEphemerisVenus6, testEphemeris(12).data(), 0), m.WriteBuf.Bytes())
+}
+
+func TestValidEphemerisIgnoresReserved(t *testing.T) {
+	e := Ephemeris{SVID: 3}
+	e.Subframes[0][0] = 0xff
+	assert.False(t, e.Valid())
+}
@@ -0,0 +1,182 @@
+package skytraq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// GPS ephemeris is typically usable for around four hours after it is broadcast
+	DefaultEphemerisMaxAge = 4 * time.Hour
+
+	ephemerisCacheVersion = 1
+)
+
+// allow mocking
+var timeNow = time.Now
+
+// Stores ephemeris downloaded from a device in a file so that it can be uploaded again after the
+// device has lost power, avoiding a cold start.
+type EphemerisCache struct {
+	Path   string
+	MaxAge time.Duration // entries older than this are discarded
+}
+
+type ephemerisCacheFile struct {
+	Version int                    `json:"version"`
+	Entries []ephemerisCacheRecord `json:"entries"`
+}
+
+type ephemerisCacheRecord struct {
+	SVID  int       `json:"svid"`
+	Saved time.Time `json:"saved"`
+	Data  []byte    `json:"data"` // 0xB1 payload
+}
+
+func NewEphemerisCache(path string) *EphemerisCache {
+	return &EphemerisCache{
+		Path:   path,
+		MaxAge: DefaultEphemerisMaxAge,
+	}
+}
+
+func (ec *EphemerisCache) read() ([]ephemerisCacheRecord, error) {
+	buf, err := ioutil.ReadFile(ec.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var file ephemerisCacheFile
+	if err := json.Unmarshal(buf, &file); err != nil {
+		return nil, errors.Wrapf(err, "unable to decode ephemeris cache %v", ec.Path)
+	}
+	if file.Version != ephemerisCacheVersion {
+		return nil, errors.Errorf("unsupported ephemeris cache version %v", file.Version)
+	}
+
+	// drop stale entries
+	records := []ephemerisCacheRecord{}
+	now := timeNow()
+	for _, r := range file.Entries {
+		if now.Sub(r.Saved) <= ec.MaxAge {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+// Load the ephemeris in the cache that is not stale. A missing cache file is treated as empty.
+func (ec *EphemerisCache) Load() ([]Ephemeris, error) {
+	records, err := ec.read()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Ephemeris{}
+	for _, r := range records {
+		f := Frame{
+			ID:   ResponseEphemerisData,
+			Data: r.Data,
+		}
+		e, err := f.ephemeris()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid ephemeris cache entry for SV %v", r.SVID)
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// Save ephemeris to the cache, replacing entries for the same satellites and discarding stale
+// entries. An entry whose ephemeris is unchanged keeps the time it was first saved, so ephemeris
+// restored to the device and downloaded again still becomes stale. The file is replaced atomically.
+func (ec *EphemerisCache) Save(eph []Ephemeris) error {
+	records, err := ec.read()
+	if err != nil {
+		logrus.Warnf("discarding unreadable ephemeris cache: %v", err)
+		records = nil
+	}
+
+	now := timeNow()
+	bySV := map[int]ephemerisCacheRecord{}
+	for _, r := range records {
+		bySV[r.SVID] = r
+	}
+	for _, e := range eph {
+		if !e.Valid() {
+			continue
+		}
+		data := e.data()
+		if r, ok := bySV[e.SVID]; ok && bytes.Equal(r.Data, data) {
+			continue
+		}
+		bySV[e.SVID] = ephemerisCacheRecord{
+			SVID:  e.SVID,
+			Saved: now,
+			Data:  data,
+		}
+	}
+
+	file := ephemerisCacheFile{
+		Version: ephemerisCacheVersion,
+		Entries: []ephemerisCacheRecord{},
+	}
+	for sv := 1; sv <= maxGPSSVID; sv++ {
+		if r, ok := bySV[sv]; ok {
+			file.Entries = append(file.Entries, r)
+		}
+	}
+	buf, err := json.Marshal(&file)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(ec.Path), filepath.Base(ec.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), ec.Path)
+}
+
+// Download ephemeris for all satellites from the device and save it to the cache.
+func (c *Connection) SaveEphemeris(ctx context.Context, cache *EphemerisCache) error {
+	eph, err := c.GetEphemeris(ctx, 0)
+	if err != nil {
+		return err
+	}
+	logrus.WithField("satellites", len(eph)).Info("saving ephemeris")
+	return cache.Save(eph)
+}
+
+// Upload the ephemeris in the cache that is not stale to the device.
+func (c *Connection) RestoreEphemeris(ctx context.Context, cache *EphemerisCache) error {
+	eph, err := cache.Load()
+	if err != nil {
+		return err
+	}
+	logrus.WithField("satellites", len(eph)).Info("restoring ephemeris")
+	for _, e := range eph {
+		if err := c.SetEphemeris(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package skytraq
+
+import (
+	"context"
+	"github.com/jd3nn1s/serial"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func ephemerisCache(t *testing.T) (*EphemerisCache, func()) {
+	dir, err := ioutil.TempDir("", "ephemeris")
+	assert.NoError(t, err)
+
+	oldTimeNow := timeNow
+	return NewEphemerisCache(filepath.Join(dir, "ephemeris.json")), func() {
+		timeNow = oldTimeNow
+		os.RemoveAll(dir)
+	}
+}
+
+func setTime(tm time.Time) {
+	timeNow = func() time.Time {
+		return tm
+	}
+}
+
+func TestEphemerisCacheMissing(t *testing.T) {
+	cache, cleanup := ephemerisCache(t)
+	defer cleanup()
+
+	eph, err := cache.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, eph)
+}
+
+func TestEphemerisCacheSaveLoad(t *testing.T) {
+	cache, cleanup := ephemerisCache(t)
+	defer cleanup()
+	start := time.Date(2022, time.March, 7, 12, 0, 0, 0, time.UTC)
+
+	setTime(start)
+	assert.NoError(t, cache.Save([]Ephemeris{testEphemeris(3), testEphemeris(1), {SVID: 2}}))
+
+	setTime(start.Add(3 * time.Hour))
+	updated := testEphemeris(3)
+	updated.Subframes[0][0] = 0xff
+	assert.NoError(t, cache.Save([]Ephemeris{updated, testEphemeris(4)}))
+
+	eph, err := cache.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []Ephemeris{testEphemeris(1), updated, testEphemeris(4)}, eph)
+
+	// SV 1 becomes stale
+	setTime(start.Add(4*time.Hour + time.Second))
+	eph, err = cache.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []Ephemeris{updated, testEphemeris(4)}, eph)
+
+	// stale entries are removed from the file
+	assert.NoError(t, cache.Save(nil))
+	cache.MaxAge = 24 * time.Hour
+	eph, err = cache.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []Ephemeris{updated, testEphemeris(4)}, eph)
+}
+
+func TestEphemerisCacheResaveKeepsAge(t *testing.T) {
+	cache, cleanup := ephemerisCache(t)
+	defer cleanup()
+	start := time.Date(2022, time.March, 7, 12, 0, 0, 0, time.UTC)
+
+	setTime(start)
+	assert.NoError(t, cache.Save([]Ephemeris{testEphemeris(1)}))
+
+	// restored to the device and downloaded again unchanged
+	setTime(start.Add(3 * time.Hour))
+	eph, err := cache.Load()
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Save(eph))
+
+	setTime(start.Add(4*time.Hour + time.Second))
+	eph, err = cache.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, eph)
+}
+
+func TestEphemerisCacheCorrupt(t *testing.T) {
+	cache, cleanup := ephemerisCache(t)
+	defer cleanup()
+
+	assert.NoError(t, ioutil.WriteFile(cache.Path, []byte("{"), 0644))
+	_, err := cache.Load()
+	assert.Error(t, err)
+
+	// a corrupt cache is replaced when saving
+	assert.NoError(t, cache.Save([]Ephemeris{testEphemeris(1)}))
+	eph, err := cache.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []Ephemeris{testEphemeris(1)}, eph)
+
+	assert.NoError(t, ioutil.WriteFile(cache.Path, []byte(`{"version":2}`), 0644))
+	_, err = cache.Load()
+	assert.EqualError(t, err, "unsupported ephemeris cache version 2")
+}
+
+func TestSaveEphemeris(t *testing.T) {
+	cache, cleanup := ephemerisCache(t)
+	defer cleanup()
+
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandGetEphermeris)}, 0))
+	for sv := 1; sv <= maxGPSSVID; sv++ {
+		e := Ephemeris{SVID: sv}
+		if sv == 5 {
+			e = testEphemeris(sv)
+		}
+		m.ReadBuf.Write(frameData(ResponseEphemerisData, e.data(), 0))
+	}
+
+	assert.NoError(t, c.SaveEphemeris(context.Background(), cache))
+	eph, err := cache.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []Ephemeris{testEphemeris(5)}, eph)
+}
+
+func TestConnectRestoresEphemeris(t *testing.T) {
+	cache, cleanup := ephemerisCache(t)
+	defer cleanup()
+	assert.NoError(t, cache.Save([]Ephemeris{testEphemeris(2), testEphemeris(9)}))
+
+	oldOpenPort := openPort
+	defer func() {
+		openPort = oldOpenPort
+	}()
+	m := MockSerialPort{}
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		return &m, nil
+	}
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandSetEphemeris)}, 0))
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandSetEphemeris)}, 0))
+
+	_, err := ConnectWithOptions("fakeport", WithEphemerisCache(cache))
+	assert.NoError(t, err)
+
+	expected := frameData(CommandQuerySoftwareVersion, []byte{1}, 0)
+	expected = append(expected, frameData(CommandSetEphemeris, testEphemeris(2).data(), 0)...)
+	expected = append(expected, frameData(CommandSetEphemeris, testEphemeris(9).data(), 0)...)
+	assert.Equal(t, expected, m.WriteBuf.Bytes())
+}
+
+func TestConnectRestoreEphemerisFailure(t *testing.T) {
+	cache, cleanup := ephemerisCache(t)
+	defer cleanup()
+	assert.NoError(t, ioutil.WriteFile(cache.Path, []byte("{"), 0644))
+
+	oldOpenPort := openPort
+	defer func() {
+		openPort = oldOpenPort
+	}()
+	m := MockSerialPort{}
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		return &m, nil
+	}
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+
+	_, err := ConnectWithOptions("fakeport", WithEphemerisCache(cache))
+	assert.NoError(t, err)
+}
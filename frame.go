@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"time"
 )
 
 type MessageID byte
@@ -25,18 +26,40 @@ type SoftwareVersion struct {
 	Revision Version
 }
 
+// CRC of the device's system code
+type SoftwareCRC uint16
+
+// Rate in Hz at which the device updates its position
+type PositionRate int
+
+type PowerMode uint8
+
 type FixMode uint8
 
+// Navigation data message (0xA8). Values are as sent by the device: latitude and longitude are in
+// units of 1e-7 degrees, altitudes and ECEF coordinates in centimetres, velocities in cm/s, time of
+// week in units of 10ms and dilutions of precision scaled by 100.
 type NavData struct {
-	Fix            FixMode
-	SatelliteCount int
-	Latitude       int
-	Longitude      int
-	Altitude       int
-	VX             int
-	VY             int
-	VZ             int
-	HDOP           int
+	Fix               FixMode
+	SatelliteCount    int
+	GPSWeek           int
+	TimeOfWeek        int
+	Latitude          int
+	Longitude         int
+	EllipsoidAltitude int
+	Altitude          int // mean sea level
+	GDOP              int
+	PDOP              int
+	HDOP              int
+	VDOP              int
+	TDOP              int
+	X                 int
+	Y                 int
+	Z                 int
+	VX                int
+	VY                int
+	VZ                int
+	Time              GPSTime // decoded from GPSWeek and TimeOfWeek
 }
 
 func (sv Version) String() string {
@@ -48,7 +71,22 @@ func (sv SoftwareVersion) String() string {
 		sv.Kernel, sv.ODM, sv.Revision)
 }
 
+func (crc SoftwareCRC) String() string {
+	return fmt.Sprintf("%04X", uint16(crc))
+}
+
+func (pm PowerMode) String() string {
+	switch pm {
+	case PowerModeNormal:
+		return "normal"
+	case PowerSave:
+		return "power save"
+	}
+	return fmt.Sprintf("unknown (%v)", uint8(pm))
+}
+
 const (
+	ResponseExtended        MessageID = 0x64
 	ResponseSoftwareVersion MessageID = 0x80
 	ResponseSoftwareCRC     MessageID = 0x81
 	ResponseACK             MessageID = 0x83
@@ -57,15 +95,43 @@ const (
 	ResponseNavData         MessageID = 0xA8
 	ResponseEphemerisData   MessageID = 0xB1
 	ResponsePowerMode       MessageID = 0xB9
+
+	// raw measurement output
+	ResponseMeasurementTime  MessageID = 0xDC
+	ResponseRawMeasurements  MessageID = 0xDD
+	ResponseSVChannelStatus  MessageID = 0xDE
+	ResponseReceiverNavState MessageID = 0xDF
+	ResponseSubframe         MessageID = 0xE0
+)
+
+const (
+	CommandSystemRestart              MessageID = 0x01
+	CommandQuerySoftwareVersion       MessageID = 0x02
+	CommandQuerySoftwareCRC           MessageID = 0x03
+	CommandSetFactoryDefaults         MessageID = 0x04
+	CommandConfigureSerialPort        MessageID = 0x05
+	CommandConfigureNMEA              MessageID = 0x08
+	CommandConfigureMessageType       MessageID = 0x09
+	CommandConfigurePowerMode         MessageID = 0x0C
+	CommandConfigurePositionRate      MessageID = 0x0E
+	CommandQueryPositionRate          MessageID = 0x10
+	CommandQueryPowerMode             MessageID = 0x15
+	CommandConfigureBinaryMeasurement MessageID = 0x1E
+	CommandGetEphermeris              MessageID = 0x30
+	CommandSetEphemerisVenus6         MessageID = 0x31
+	CommandSetEphemeris               MessageID = 0x41
+	CommandExtended                   MessageID = 0x64
+)
+
+// Extended messages carry a sub-ID as the first data byte
+const (
+	subIDQueryGPSTime = 0x20
+	subIDGPSTime      = 0x8E
 )
 
 const (
-	CommandSystemRestart        MessageID = 0x01
-	CommandQuerySoftwareVersion MessageID = 0x02
-	CommandQuerySoftwareCRC     MessageID = 0x03
-	CommandQueryPositionRate    MessageID = 0x10
-	CommandQueryPowerMode       MessageID = 0x15
-	CommandGetEphermeris        MessageID = 0x30
+	PowerModeNormal PowerMode = 0
+	PowerSave       PowerMode = 1
 )
 
 const (
@@ -112,14 +178,104 @@ func (f *Frame) navData() (NavData, error) {
 	}
 
 	return NavData{
-		Fix:            FixMode(f.Data[0]),
-		SatelliteCount: int(f.Data[2]),
-		Latitude:       int(binary.BigEndian.Uint32(f.Data[8:12])),
-		Longitude:      int(binary.BigEndian.Uint32(f.Data[12:16])),
-		Altitude:       int(binary.BigEndian.Uint32(f.Data[20:24])),
-		HDOP:           int(binary.BigEndian.Uint16(f.Data[28:30])),
-		VX:             int(binary.BigEndian.Uint32(f.Data[46:50])),
-		VY:             int(binary.BigEndian.Uint32(f.Data[50:54])),
-		VZ:             int(binary.BigEndian.Uint32(f.Data[54:58])),
+		Fix:               FixMode(f.Data[0]),
+		SatelliteCount:    int(f.Data[1]),
+		GPSWeek:           int(binary.BigEndian.Uint16(f.Data[2:4])),
+		TimeOfWeek:        int(binary.BigEndian.Uint32(f.Data[4:8])),
+		Latitude:          int(int32(binary.BigEndian.Uint32(f.Data[8:12]))),
+		Longitude:         int(int32(binary.BigEndian.Uint32(f.Data[12:16]))),
+		EllipsoidAltitude: int(int32(binary.BigEndian.Uint32(f.Data[16:20]))),
+		Altitude:          int(int32(binary.BigEndian.Uint32(f.Data[20:24]))),
+		GDOP:              int(binary.BigEndian.Uint16(f.Data[24:26])),
+		PDOP:              int(binary.BigEndian.Uint16(f.Data[26:28])),
+		HDOP:              int(binary.BigEndian.Uint16(f.Data[28:30])),
+		VDOP:              int(binary.BigEndian.Uint16(f.Data[30:32])),
+		TDOP:              int(binary.BigEndian.Uint16(f.Data[32:34])),
+		X:                 int(int32(binary.BigEndian.Uint32(f.Data[34:38]))),
+		Y:                 int(int32(binary.BigEndian.Uint32(f.Data[38:42]))),
+		Z:                 int(int32(binary.BigEndian.Uint32(f.Data[42:46]))),
+		VX:                int(int32(binary.BigEndian.Uint32(f.Data[46:50]))),
+		VY:                int(int32(binary.BigEndian.Uint32(f.Data[50:54]))),
+		VZ:                int(int32(binary.BigEndian.Uint32(f.Data[54:58]))),
+		Time: newGPSTime(int(binary.BigEndian.Uint16(f.Data[2:4])),
+			time.Duration(binary.BigEndian.Uint32(f.Data[4:8]))*10*time.Millisecond),
 	}, nil
 }
+
+// Latitude in degrees
+func (nd NavData) LatitudeDegrees() float64 {
+	return float64(nd.Latitude) / 1e7
+}
+
+// Longitude in degrees
+func (nd NavData) LongitudeDegrees() float64 {
+	return float64(nd.Longitude) / 1e7
+}
+
+// Altitude above mean sea level in meters
+func (nd NavData) AltitudeMeters() float64 {
+	return float64(nd.Altitude) / 100
+}
+
+// Altitude above the WGS-84 ellipsoid in meters
+func (nd NavData) EllipsoidAltitudeMeters() float64 {
+	return float64(nd.EllipsoidAltitude) / 100
+}
+
+func (nd NavData) GDOPValue() float64 {
+	return float64(nd.GDOP) / 100
+}
+
+func (nd NavData) PDOPValue() float64 {
+	return float64(nd.PDOP) / 100
+}
+
+func (nd NavData) HDOPValue() float64 {
+	return float64(nd.HDOP) / 100
+}
+
+func (nd NavData) VDOPValue() float64 {
+	return float64(nd.VDOP) / 100
+}
+
+func (nd NavData) TDOPValue() float64 {
+	return float64(nd.TDOP) / 100
+}
+
+// ECEF velocity in m/s
+func (nd NavData) Velocity() (vx, vy, vz float64) {
+	return float64(nd.VX) / 100, float64(nd.VY) / 100, float64(nd.VZ) / 100
+}
+
+func (f *Frame) softwareCRC() (SoftwareCRC, error) {
+	const expectedLen = 3
+	if len(f.Data) != expectedLen {
+		logrus.WithField("length", len(f.Data)).
+			WithField("expectedLen", expectedLen).Error("expecting more data")
+		return 0, errors.Errorf("softwareCRC conversion requires %v bytes but received %v",
+			expectedLen, len(f.Data))
+	}
+	return SoftwareCRC(binary.BigEndian.Uint16(f.Data[1:3])), nil
+}
+
+func (f *Frame) positionRate() (PositionRate, error) {
+	const expectedLen = 1
+	if len(f.Data) != expectedLen {
+		logrus.WithField("length", len(f.Data)).
+			WithField("expectedLen", expectedLen).Error("expecting more data")
+		return 0, errors.Errorf("positionRate conversion requires %v bytes but received %v",
+			expectedLen, len(f.Data))
+	}
+	return PositionRate(f.Data[0]), nil
+}
+
+func (f *Frame) powerMode() (PowerMode, error) {
+	const expectedLen = 1
+	if len(f.Data) != expectedLen {
+		logrus.WithField("length", len(f.Data)).
+			WithField("expectedLen", expectedLen).Error("expecting more data")
+		return 0, errors.Errorf("powerMode conversion requires %v bytes but received %v",
+			expectedLen, len(f.Data))
+	}
+	return PowerMode(f.Data[0]), nil
+}
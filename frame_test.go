@@ -0,0 +1,129 @@
+package skytraq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// encode NavData into an 0xA8 payload
+func navDataBytes(nd NavData) []byte {
+	buf := bytes.Buffer{}
+	buf.Write([]byte{byte(nd.Fix), byte(nd.SatelliteCount)})
+	binary.Write(&buf, binary.BigEndian, uint16(nd.GPSWeek))
+	binary.Write(&buf, binary.BigEndian, uint32(nd.TimeOfWeek))
+	for _, v := range []int{nd.Latitude, nd.Longitude, nd.EllipsoidAltitude, nd.Altitude} {
+		binary.Write(&buf, binary.BigEndian, int32(v))
+	}
+	for _, v := range []int{nd.GDOP, nd.PDOP, nd.HDOP, nd.VDOP, nd.TDOP} {
+		binary.Write(&buf, binary.BigEndian, uint16(v))
+	}
+	for _, v := range []int{nd.X, nd.Y, nd.Z, nd.VX, nd.VY, nd.VZ} {
+		binary.Write(&buf, binary.BigEndian, int32(v))
+	}
+	return buf.Bytes()
+}
+
+func TestNavData(t *testing.T) {
+	tests := []struct {
+		name string
+		nd   NavData
+	}{
+		{"zero", NavData{}},
+		{"northEast", NavData{
+			Fix:               Fix3D,
+			SatelliteCount:    9,
+			GPSWeek:           2000,
+			TimeOfWeek:        36000000,
+			Latitude:          515007000,
+			Longitude:         1246000,
+			EllipsoidAltitude: 7105,
+			Altitude:          2400,
+			GDOP:              210,
+			PDOP:              180,
+			HDOP:              95,
+			VDOP:              150,
+			TDOP:              110,
+			X:                 397776538,
+			Y:                 8650123,
+			Z:                 496855347,
+			VX:                12,
+			VY:                34,
+			VZ:                56,
+		}},
+		{"southWest", NavData{
+			Fix:               Fix2D,
+			SatelliteCount:    4,
+			GPSWeek:           2100,
+			TimeOfWeek:        1,
+			Latitude:          -338688000,
+			Longitude:         -1512093000,
+			EllipsoidAltitude: -1500,
+			Altitude:          -3700,
+			X:                 -464687224,
+			Y:                 -256870456,
+			Z:                 -353585637,
+			VX:                -250,
+			VY:                -1,
+			VZ:                -2147483648,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Frame{
+				ID:   ResponseNavData,
+				Data: navDataBytes(tt.nd),
+			}
+			expected := tt.nd
+			expected.Time = newGPSTime(tt.nd.GPSWeek, time.Duration(tt.nd.TimeOfWeek)*10*time.Millisecond)
+
+			nd, err := f.navData()
+			assert.NoError(t, err)
+			assert.Equal(t, expected, nd)
+		})
+	}
+}
+
+func TestNavDataShort(t *testing.T) {
+	f := Frame{
+		ID:   ResponseNavData,
+		Data: navDataBytes(NavData{})[:57],
+	}
+	_, err := f.navData()
+	assert.EqualError(t, err, "navdata conversion requires 58 bytes but received 57")
+}
+
+func TestNavDataUnits(t *testing.T) {
+	nd := NavData{
+		Latitude:          -338688000,
+		Longitude:         1512093000,
+		EllipsoidAltitude: -1550,
+		Altitude:          2400,
+		GDOP:              210,
+		PDOP:              180,
+		HDOP:              95,
+		VDOP:              150,
+		TDOP:              110,
+		VX:                -250,
+		VY:                1,
+		VZ:                1000,
+	}
+
+	assert.InDelta(t, -33.8688, nd.LatitudeDegrees(), 1e-9)
+	assert.InDelta(t, 151.2093, nd.LongitudeDegrees(), 1e-9)
+	assert.InDelta(t, 24.0, nd.AltitudeMeters(), 1e-9)
+	assert.InDelta(t, -15.5, nd.EllipsoidAltitudeMeters(), 1e-9)
+	assert.InDelta(t, 2.1, nd.GDOPValue(), 1e-9)
+	assert.InDelta(t, 1.8, nd.PDOPValue(), 1e-9)
+	assert.InDelta(t, 0.95, nd.HDOPValue(), 1e-9)
+	assert.InDelta(t, 1.5, nd.VDOPValue(), 1e-9)
+	assert.InDelta(t, 1.1, nd.TDOPValue(), 1e-9)
+
+	vx, vy, vz := nd.Velocity()
+	assert.InDelta(t, -2.5, vx, 1e-9)
+	assert.InDelta(t, 0.01, vy, 1e-9)
+	assert.InDelta(t, 10.0, vz, 1e-9)
+}
@@ -0,0 +1,163 @@
+package skytraq
+
+import (
+	"math"
+)
+
+// WGS-84 ellipsoid
+const (
+	wgs84A  = 6378137.0
+	wgs84F  = 1 / 298.257223563
+	wgs84E2 = wgs84F * (2 - wgs84F)
+)
+
+const (
+	metersPerSecondToKPH   = 3.6
+	metersPerSecondToKnots = 3600.0 / 1852.0
+)
+
+// Earth-centered, earth-fixed coordinates in meters.
+type ECEF struct {
+	X float64
+	Y float64
+	Z float64
+}
+
+// Geodetic WGS-84 coordinates. Latitude and longitude are in degrees, altitude is in meters above
+// the ellipsoid.
+type LLA struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// A vector in a local East-North-Up frame, in meters or m/s depending on the source.
+type ENU struct {
+	East  float64
+	North float64
+	Up    float64
+}
+
+// Rotate an ECEF vector into the local East-North-Up frame at the supplied latitude and longitude,
+// given in degrees.
+func ecefToENU(x, y, z, lat, lon float64) ENU {
+	sinLat, cosLat := math.Sincos(lat * math.Pi / 180)
+	sinLon, cosLon := math.Sincos(lon * math.Pi / 180)
+
+	return ENU{
+		East:  -sinLon*x + cosLon*y,
+		North: -sinLat*cosLon*x - sinLat*sinLon*y + cosLat*z,
+		Up:    cosLat*cosLon*x + cosLat*sinLon*y + sinLat*z,
+	}
+}
+
+// Convert geodetic coordinates to ECEF.
+func (p LLA) ECEF() ECEF {
+	sinLat, cosLat := math.Sincos(p.Latitude * math.Pi / 180)
+	sinLon, cosLon := math.Sincos(p.Longitude * math.Pi / 180)
+	n := wgs84A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+
+	return ECEF{
+		X: (n + p.Altitude) * cosLat * cosLon,
+		Y: (n + p.Altitude) * cosLat * sinLon,
+		Z: (n*(1-wgs84E2) + p.Altitude) * sinLat,
+	}
+}
+
+// Convert ECEF coordinates to geodetic coordinates. Latitude is found by iteration, which converges
+// to well below a millimetre within a few steps for any point near the earth's surface.
+func (p ECEF) LLA() LLA {
+	const maxIterations = 10
+
+	r := math.Hypot(p.X, p.Y)
+	lon := math.Atan2(p.Y, p.X)
+	lat := math.Atan2(p.Z, r*(1-wgs84E2))
+
+	var alt float64
+	for i := 0; i < maxIterations; i++ {
+		sinLat, cosLat := math.Sincos(lat)
+		n := wgs84A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+		alt = r*cosLat + p.Z*sinLat - wgs84A*wgs84A/n
+		next := math.Atan2(p.Z, r*(1-wgs84E2*n/(n+alt)))
+		if math.Abs(next-lat) < 1e-12 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+
+	sinLat, cosLat := math.Sincos(lat)
+	n := wgs84A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+	alt = r*cosLat + p.Z*sinLat - wgs84A*wgs84A/n
+
+	return LLA{
+		Latitude:  lat * 180 / math.Pi,
+		Longitude: lon * 180 / math.Pi,
+		Altitude:  alt,
+	}
+}
+
+// Position of p in the local East-North-Up frame centered on ref.
+func (p ECEF) ENU(ref LLA) ENU {
+	origin := ref.ECEF()
+	return ecefToENU(p.X-origin.X, p.Y-origin.Y, p.Z-origin.Z, ref.Latitude, ref.Longitude)
+}
+
+// Position of p in the local East-North-Up frame centered on ref.
+func (p LLA) ENU(ref LLA) ENU {
+	return p.ECEF().ENU(ref)
+}
+
+// Reported position as geodetic coordinates, using the altitude above the ellipsoid.
+func (nd NavData) Position() LLA {
+	return LLA{
+		Latitude:  nd.LatitudeDegrees(),
+		Longitude: nd.LongitudeDegrees(),
+		Altitude:  nd.EllipsoidAltitudeMeters(),
+	}
+}
+
+// Reported position as ECEF coordinates.
+func (nd NavData) PositionECEF() ECEF {
+	return ECEF{
+		X: float64(nd.X) / 100,
+		Y: float64(nd.Y) / 100,
+		Z: float64(nd.Z) / 100,
+	}
+}
+
+// Velocity in m/s in the local East-North-Up frame at the reported position.
+func (nd NavData) VelocityENU() ENU {
+	vx, vy, vz := nd.Velocity()
+	return ecefToENU(vx, vy, vz, nd.LatitudeDegrees(), nd.LongitudeDegrees())
+}
+
+// Horizontal speed in m/s.
+func (v ENU) GroundSpeed() float64 {
+	return math.Hypot(v.East, v.North)
+}
+
+// Horizontal speed in km/h.
+func (v ENU) GroundSpeedKPH() float64 {
+	return v.GroundSpeed() * metersPerSecondToKPH
+}
+
+// Horizontal speed in knots.
+func (v ENU) GroundSpeedKnots() float64 {
+	return v.GroundSpeed() * metersPerSecondToKnots
+}
+
+// True course over ground in degrees, from 0 up to but not including 360. A stationary vector
+// has a course of 0.
+func (v ENU) Course() float64 {
+	course := math.Atan2(v.East, v.North) * 180 / math.Pi
+	if course < 0 {
+		course += 360
+	}
+	return course
+}
+
+// Vertical speed in m/s, positive when climbing.
+func (v ENU) VerticalSpeed() float64 {
+	return v.Up
+}
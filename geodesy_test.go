@@ -0,0 +1,189 @@
+package skytraq
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestVelocityENU(t *testing.T) {
+	tests := []struct {
+		name       string
+		nd         NavData
+		enu        ENU
+		speed      float64
+		course     float64
+		climbSpeed float64
+	}{
+		{
+			name:   "equatorEast",
+			nd:     NavData{VY: 100},
+			enu:    ENU{East: 1},
+			speed:  1,
+			course: 90,
+		},
+		{
+			name:       "equatorUp",
+			nd:         NavData{VX: 250},
+			enu:        ENU{Up: 2.5},
+			course:     0,
+			climbSpeed: 2.5,
+		},
+		{
+			name:   "45NorthNorth",
+			nd:     NavData{Latitude: 450000000, VX: -100, VZ: 100},
+			enu:    ENU{North: math.Sqrt2},
+			speed:  math.Sqrt2,
+			course: 0,
+		},
+		{
+			name:   "90EastWest",
+			nd:     NavData{Longitude: 900000000, VX: 300},
+			enu:    ENU{East: -3},
+			speed:  3,
+			course: 270,
+		},
+		{
+			name:       "southPoleDescending",
+			nd:         NavData{Latitude: -900000000, VZ: 50},
+			enu:        ENU{Up: -0.5},
+			climbSpeed: -0.5,
+		},
+		{
+			name:   "southWest",
+			nd:     NavData{VY: -100, VZ: -100},
+			enu:    ENU{East: -1, North: -1},
+			speed:  math.Sqrt2,
+			course: 225,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.nd.VelocityENU()
+			assert.InDelta(t, tt.enu.East, v.East, 1e-9)
+			assert.InDelta(t, tt.enu.North, v.North, 1e-9)
+			assert.InDelta(t, tt.enu.Up, v.Up, 1e-9)
+			assert.InDelta(t, tt.speed, v.GroundSpeed(), 1e-9)
+			assert.InDelta(t, tt.course, v.Course(), 1e-6)
+			assert.InDelta(t, tt.climbSpeed, v.VerticalSpeed(), 1e-9)
+		})
+	}
+}
+
+func TestVelocityENURotation(t *testing.T) {
+	// Sydney, travelling north east at 10 m/s while climbing at 1 m/s
+	lat, lon := -33.8688, 151.2093
+	e, n, u := 10*math.Sin(math.Pi/4), 10*math.Cos(math.Pi/4), 1.0
+
+	// rotate the expected ENU vector back into ECEF
+	sinLat, cosLat := math.Sincos(lat * math.Pi / 180)
+	sinLon, cosLon := math.Sincos(lon * math.Pi / 180)
+	x := -sinLon*e - sinLat*cosLon*n + cosLat*cosLon*u
+	y := cosLon*e - sinLat*sinLon*n + cosLat*sinLon*u
+	z := cosLat*n + sinLat*u
+
+	nd := NavData{
+		Latitude:  int(lat * 1e7),
+		Longitude: int(lon * 1e7),
+		VX:        int(math.Round(x * 100)),
+		VY:        int(math.Round(y * 100)),
+		VZ:        int(math.Round(z * 100)),
+	}
+	v := nd.VelocityENU()
+	assert.InDelta(t, 10, v.GroundSpeed(), 0.01)
+	assert.InDelta(t, 45, v.Course(), 0.1)
+	assert.InDelta(t, 1, v.VerticalSpeed(), 0.01)
+}
+
+func TestGroundSpeedUnits(t *testing.T) {
+	v := ENU{East: 3, North: 4}
+	assert.InDelta(t, 5, v.GroundSpeed(), 1e-9)
+	assert.InDelta(t, 18, v.GroundSpeedKPH(), 1e-9)
+	assert.InDelta(t, 9.719222, v.GroundSpeedKnots(), 1e-6)
+}
+
+func TestLLAToECEF(t *testing.T) {
+	tests := []struct {
+		name string
+		lla  LLA
+		ecef ECEF
+	}{
+		{"origin", LLA{0, 0, 0}, ECEF{6378137, 0, 0}},
+		{"equator90East", LLA{0, 90, 100}, ECEF{0, 6378237, 0}},
+		{"northPole", LLA{90, 0, 0}, ECEF{0, 0, 6356752.314245}},
+		{"southPole", LLA{-90, 0, -10}, ECEF{0, 0, -6356742.314245}},
+		{"sydney", LLA{-33.8688, 151.2093, 0}, ECEF{-4646051.272, 2553206.342, -3534372.388}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ecef := tt.lla.ECEF()
+			assert.InDelta(t, tt.ecef.X, ecef.X, 1e-3)
+			assert.InDelta(t, tt.ecef.Y, ecef.Y, 1e-3)
+			assert.InDelta(t, tt.ecef.Z, ecef.Z, 1e-3)
+
+			lla := tt.ecef.LLA()
+			assert.InDelta(t, tt.lla.Latitude, lla.Latitude, 1e-8)
+			if math.Abs(tt.lla.Latitude) != 90 {
+				assert.InDelta(t, tt.lla.Longitude, lla.Longitude, 1e-8)
+			}
+			assert.InDelta(t, tt.lla.Altitude, lla.Altitude, 1e-3)
+		})
+	}
+}
+
+func TestECEFRoundTrip(t *testing.T) {
+	for lat := -89.5; lat <= 90; lat += 10 {
+		for lon := -180.0; lon < 180; lon += 15 {
+			for _, alt := range []float64{-100, 0, 8848, 20200000} {
+				p := LLA{lat, lon, alt}
+				rt := p.ECEF().LLA()
+				assert.InDelta(t, p.Latitude, rt.Latitude, 1e-9)
+				assert.InDelta(t, p.Longitude, rt.Longitude, 1e-9)
+				assert.InDelta(t, p.Altitude, rt.Altitude, 1e-4)
+			}
+		}
+	}
+}
+
+func TestENU(t *testing.T) {
+	ref := LLA{45, 10, 100}
+
+	origin := ref.ENU(ref)
+	assert.InDelta(t, 0, origin.East, 1e-6)
+	assert.InDelta(t, 0, origin.North, 1e-6)
+	assert.InDelta(t, 0, origin.Up, 1e-6)
+
+	above := LLA{45, 10, 150}.ENU(ref)
+	assert.InDelta(t, 0, above.East, 1e-6)
+	assert.InDelta(t, 0, above.North, 1e-6)
+	assert.InDelta(t, 50, above.Up, 1e-6)
+
+	// a short baseline due east is approximately the arc length along the parallel
+	east := LLA{45, 10.001, 100}.ENU(ref)
+	n := wgs84A / math.Sqrt(1-wgs84E2*0.5)
+	assert.InDelta(t, n*math.Cos(math.Pi/4)*0.001*math.Pi/180, east.East, 1e-2)
+	assert.InDelta(t, 0, east.North, 1e-3)
+	assert.InDelta(t, 90, east.Course(), 1e-3)
+}
+
+func TestNavDataPosition(t *testing.T) {
+	nd := NavData{
+		Latitude:          -338688000,
+		Longitude:         1512093000,
+		EllipsoidAltitude: 2550,
+		X:                 -464686199,
+		Y:                 255346839,
+		Z:                 -353435983,
+	}
+	p := nd.Position()
+	assert.InDelta(t, -33.8688, p.Latitude, 1e-9)
+	assert.InDelta(t, 151.2093, p.Longitude, 1e-9)
+	assert.InDelta(t, 25.5, p.Altitude, 1e-9)
+
+	ecef := nd.PositionECEF()
+	assert.InDelta(t, -4646861.99, ecef.X, 1e-9)
+	assert.InDelta(t, 2553468.39, ecef.Y, 1e-9)
+	assert.InDelta(t, -3534359.83, ecef.Z, 1e-9)
+}
@@ -0,0 +1,122 @@
+package skytraq
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	weekDuration = 7 * 24 * time.Hour
+	weekRollover = 1024
+
+	// validity flags of the GPS time response
+	gpsTimeTOWValid         = 1 << 0
+	gpsTimeWeekValid        = 1 << 1
+	gpsTimeLeapSecondsValid = 1 << 2
+)
+
+var gpsEpoch = time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// Difference between GPS time and UTC in seconds used when decoding times from the device. Can be
+// changed before connecting, or replaced with the device's own value by calling QueryGPSTime.
+var DefaultLeapSeconds = 18
+
+// Time as reported by the device on the GPS time scale.
+type GPSTime struct {
+	Week        int // weeks since the GPS epoch
+	TimeOfWeek  time.Duration
+	LeapSeconds int // seconds GPS time is ahead of UTC
+}
+
+func newGPSTime(week int, tow time.Duration) GPSTime {
+	return GPSTime{
+		Week:        week,
+		TimeOfWeek:  tow,
+		LeapSeconds: DefaultLeapSeconds,
+	}
+}
+
+// Resolve a GPS week number truncated to 10 bits, such as the week number in the navigation
+// message, into weeks since the GPS epoch, choosing the week closest to the current date. Use
+// ResolveWeekNear for data that was not received recently.
+func ResolveWeek(week int) int {
+	return ResolveWeekNear(week, int(timeNow().Sub(gpsEpoch)/weekDuration))
+}
+
+// Resolve a GPS week number truncated to 10 bits into weeks since the GPS epoch. Of the weeks the
+// truncated number could be, the one closest to reference is chosen, so reference should be a full
+// week number known to be within about ten years, such as the week of the measurement the
+// truncated week arrived with.
+func ResolveWeekNear(week, reference int) int {
+	offset := ((week-reference)%weekRollover + weekRollover) % weekRollover
+	if offset >= weekRollover/2 {
+		offset -= weekRollover
+	}
+	return reference + offset
+}
+
+// The GPS time as a time.Time, without any correction for leap seconds.
+func (t GPSTime) Time() time.Time {
+	return gpsEpoch.Add(time.Duration(t.Week)*weekDuration + t.TimeOfWeek)
+}
+
+// The GPS time converted to UTC.
+func (t GPSTime) UTC() time.Time {
+	return t.Time().Add(-time.Duration(t.LeapSeconds) * time.Second)
+}
+
+// Query the device's GPS time. When the device reports a valid leap second count it replaces
+// DefaultLeapSeconds for times subsequently decoded by Start on this connection.
+func (c *Connection) QueryGPSTime(ctx context.Context) (GPSTime, error) {
+	f, err := c.queryFunc(ctx, &Frame{
+		ID:   CommandExtended,
+		Data: []byte{subIDQueryGPSTime},
+	}, func(f *Frame) bool {
+		return f.ID == ResponseExtended && len(f.Data) > 0 && f.Data[0] == subIDGPSTime
+	})
+	if err != nil {
+		return GPSTime{}, err
+	}
+
+	t, valid, err := f.gpsTime()
+	if err != nil {
+		return GPSTime{}, err
+	}
+	if valid&(gpsTimeTOWValid|gpsTimeWeekValid) != gpsTimeTOWValid|gpsTimeWeekValid {
+		logrus.WithField("valid", valid).Warn("device GPS time is not yet valid")
+	}
+	if valid&gpsTimeLeapSecondsValid != 0 {
+		c.leapSeconds = t.LeapSeconds
+		c.leapSecondsValid = true
+	}
+	return t, nil
+}
+
+// Decode the GPS time extended response, returning it with the validity flags. If the device has
+// not yet received a leap second count the device's default is used.
+func (f *Frame) gpsTime() (GPSTime, uint8, error) {
+	const expectedLen = 14
+	if len(f.Data) != expectedLen {
+		logrus.WithField("length", len(f.Data)).
+			WithField("expectedLen", expectedLen).Error("expecting more data")
+		return GPSTime{}, 0, errors.Errorf("gpsTime conversion requires %v bytes but received %v",
+			expectedLen, len(f.Data))
+	}
+
+	towMillis := binary.BigEndian.Uint32(f.Data[1:5])
+	towNanos := binary.BigEndian.Uint32(f.Data[5:9])
+	valid := f.Data[13]
+
+	leapSeconds := int(int8(f.Data[11]))
+	if valid&gpsTimeLeapSecondsValid != 0 {
+		leapSeconds = int(int8(f.Data[12]))
+	}
+	return GPSTime{
+		Week:        int(binary.BigEndian.Uint16(f.Data[9:11])),
+		TimeOfWeek:  time.Duration(towMillis)*time.Millisecond + time.Duration(towNanos),
+		LeapSeconds: leapSeconds,
+	}, valid, nil
+}
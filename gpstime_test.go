@@ -0,0 +1,149 @@
+package skytraq
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func gpsTimeData(towMillis uint32, week uint16, defaultLeap, currentLeap int8, valid uint8) []byte {
+	buf := bytes.Buffer{}
+	buf.WriteByte(subIDGPSTime)
+	binary.Write(&buf, binary.BigEndian, towMillis)
+	binary.Write(&buf, binary.BigEndian, uint32(500))
+	binary.Write(&buf, binary.BigEndian, week)
+	buf.Write([]byte{byte(defaultLeap), byte(currentLeap), valid})
+	return buf.Bytes()
+}
+
+func TestGPSTimeUTC(t *testing.T) {
+	tests := []struct {
+		name string
+		time GPSTime
+		utc  time.Time
+	}{
+		{
+			name: "epoch",
+			time: GPSTime{Week: 0},
+			utc:  time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "leapSeconds",
+			time: GPSTime{Week: 2200, LeapSeconds: 18},
+			utc:  time.Date(2022, time.March, 5, 23, 59, 42, 0, time.UTC),
+		},
+		{
+			name: "timeOfWeek",
+			time: GPSTime{Week: 2200, TimeOfWeek: 36*time.Hour + 1500*time.Millisecond, LeapSeconds: 18},
+			utc:  time.Date(2022, time.March, 7, 11, 59, 43, 500000000, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.utc.Equal(tt.time.UTC()), "expected %v got %v", tt.utc, tt.time.UTC())
+		})
+	}
+}
+
+func TestResolveWeekNear(t *testing.T) {
+	assert.Equal(t, 2200, ResolveWeekNear(2200%weekRollover, 2200))
+	assert.Equal(t, 2210, ResolveWeekNear(162, 2200))
+	assert.Equal(t, 2047, ResolveWeekNear(1023, 2048))
+	assert.Equal(t, 2048, ResolveWeekNear(0, 2047))
+	assert.Equal(t, 1500, ResolveWeekNear(476, 1900))
+	assert.Equal(t, 2559, ResolveWeekNear(511, 2048))
+	assert.Equal(t, 1536, ResolveWeekNear(512, 2048))
+	assert.Equal(t, 0, ResolveWeekNear(0, 0))
+}
+
+func TestResolveWeek(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	// week 2400
+	timeNow = func() time.Time {
+		return gpsEpoch.Add(2400*weekDuration + time.Hour)
+	}
+	assert.Equal(t, 2400, ResolveWeek(2400%weekRollover))
+	assert.Equal(t, 2200, ResolveWeek(2200%weekRollover))
+	assert.Equal(t, 2600, ResolveWeek(2600%weekRollover))
+}
+
+func TestNavDataTime(t *testing.T) {
+	oldLeapSeconds := DefaultLeapSeconds
+	defer func() {
+		DefaultLeapSeconds = oldLeapSeconds
+	}()
+	DefaultLeapSeconds = 17
+
+	f := Frame{
+		ID:   ResponseNavData,
+		Data: navDataBytes(NavData{GPSWeek: 2210, TimeOfWeek: 6000}),
+	}
+	nd, err := f.navData()
+	assert.NoError(t, err)
+	assert.Equal(t, GPSTime{Week: 2210, TimeOfWeek: time.Minute, LeapSeconds: 17}, nd.Time)
+
+	// full weeks from before the old rollover threshold of 2200 are not moved forward
+	f.Data = navDataBytes(NavData{GPSWeek: 2100})
+	nd, err = f.navData()
+	assert.NoError(t, err)
+	assert.Equal(t, 2100, nd.Time.Week)
+
+	// the week is a full 16 bit week number, so no rollover correction is applied before a fix
+	f.Data = navDataBytes(NavData{GPSWeek: 0})
+	nd, err = f.navData()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, nd.Time.Week)
+	assert.Equal(t, 1980, nd.Time.Time().Year())
+}
+
+func TestQueryGPSTime(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandExtended), subIDQueryGPSTime}, 0))
+	m.ReadBuf.Write(frameData(ResponseNavData, navData, 0))
+	m.ReadBuf.Write(frameData(ResponseExtended, gpsTimeData(60000, 2210, 16, 18, 7), 0))
+	m.ReadBuf.Write(frameData(ResponseNavData, navDataBytes(NavData{GPSWeek: 2210}), 0))
+	m.ReadBuf.Write(frameData(ResponseMeasurementTime, []byte{1, 0x08, 0xa2, 0, 0, 0, 0, 0, 0}, 0))
+
+	gt, err := c.QueryGPSTime(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, GPSTime{Week: 2210, TimeOfWeek: time.Minute + 500, LeapSeconds: 18}, gt)
+	assert.Equal(t, frameData(CommandExtended, []byte{subIDQueryGPSTime}, 0), m.WriteBuf.Bytes())
+
+	oldLeapSeconds := DefaultLeapSeconds
+	defer func() {
+		DefaultLeapSeconds = oldLeapSeconds
+	}()
+	DefaultLeapSeconds = 10
+
+	var leapSeconds, measurementLeapSeconds int
+	err = c.Start(context.Background(), Callbacks{
+		NavData: func(data NavData) {
+			leapSeconds = data.Time.LeapSeconds
+		},
+		MeasurementTime: func(mt MeasurementTime) {
+			measurementLeapSeconds = mt.GPSTime().LeapSeconds
+		},
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, 18, leapSeconds)
+	assert.Equal(t, 18, measurementLeapSeconds)
+}
+
+func TestQueryGPSTimeLeapSecondsInvalid(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandExtended), subIDQueryGPSTime}, 0))
+	m.ReadBuf.Write(frameData(ResponseExtended, gpsTimeData(60000, 2210, 16, 18, 3), 0))
+
+	gt, err := c.QueryGPSTime(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 16, gt.LeapSeconds)
+	assert.False(t, c.leapSecondsValid)
+}
@@ -0,0 +1,302 @@
+package skytraq
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+const (
+	rawMeasurementSize = 23
+	channelStatusSize  = 10
+	subframeWordsSize  = 30
+)
+
+// Indicates which parts of a raw measurement are valid.
+type MeasurementIndicator uint8
+
+const (
+	PseudorangeAvailable   MeasurementIndicator = 1 << 0
+	DopplerAvailable       MeasurementIndicator = 1 << 1
+	CarrierPhaseAvailable  MeasurementIndicator = 1 << 2
+	CycleSlipPossible      MeasurementIndicator = 1 << 3
+	CoherentIntegrationMin MeasurementIndicator = 1 << 4 // integration time of at least 10ms
+)
+
+// State of a satellite as tracked by the device.
+type SVStatus uint8
+
+const (
+	SVAlmanacReceived   SVStatus = 1 << 0
+	SVEphemerisReceived SVStatus = 1 << 1
+	SVHealthy           SVStatus = 1 << 2
+)
+
+// State of a receiver channel.
+type ChannelStatusIndicator uint8
+
+const (
+	ChannelPullInDone      ChannelStatusIndicator = 1 << 0
+	ChannelBitSync         ChannelStatusIndicator = 1 << 1
+	ChannelFrameSync       ChannelStatusIndicator = 1 << 2
+	ChannelEphemeris       ChannelStatusIndicator = 1 << 3
+	ChannelUsedInFix       ChannelStatusIndicator = 1 << 4
+	ChannelUsedInDGPSFix   ChannelStatusIndicator = 1 << 5
+	ChannelUsedInFixTiming ChannelStatusIndicator = 1 << 6
+)
+
+type NavigationState uint8
+
+const (
+	NavigationNoFix         NavigationState = 0
+	NavigationFixPrediction NavigationState = 1
+	Navigation2D            NavigationState = 2
+	Navigation3D            NavigationState = 3
+	NavigationDifferential  NavigationState = 4
+)
+
+// Measurement time message (0xDC). The issue of data (IOD) ties together the messages of an epoch.
+type MeasurementTime struct {
+	IOD         int
+	Week        int
+	TimeOfWeek  time.Duration
+	Period      time.Duration
+	LeapSeconds int // seconds GPS time is ahead of UTC
+}
+
+type RawMeasurement struct {
+	SVID         int
+	CN0          int     // dB-Hz
+	Pseudorange  float64 // meters
+	CarrierPhase float64 // accumulated cycles
+	Doppler      float64 // Hz
+	Indicator    MeasurementIndicator
+}
+
+// Raw measurements message (0xDD).
+type RawMeasurements struct {
+	IOD          int
+	Measurements []RawMeasurement
+}
+
+type ChannelStatus struct {
+	Channel   int
+	SVID      int
+	SVStatus  SVStatus
+	URA       int
+	CN0       int // dB-Hz
+	Elevation int // degrees
+	Azimuth   int // degrees
+	Status    ChannelStatusIndicator
+}
+
+// SV and channel status message (0xDE).
+type SVChannelStatus struct {
+	IOD      int
+	Channels []ChannelStatus
+}
+
+// Receiver navigation state message (0xDF).
+type ReceiverNavState struct {
+	IOD        int
+	State      NavigationState
+	Week       int
+	TimeOfWeek float64 // seconds
+	Position   ECEF
+	Velocity   ECEF    // m/s
+	ClockBias  float64 // meters
+	ClockDrift float64 // m/s
+	GDOP       float64
+	PDOP       float64
+	HDOP       float64
+	VDOP       float64
+	TDOP       float64
+}
+
+// GPS navigation message subframe (0xE0), ten words of 24 bits with parity removed.
+type Subframe struct {
+	SVID       int
+	SubframeID int
+	Words      [subframeWordsSize]byte
+}
+
+// Which raw measurement messages the device outputs, and how often.
+type BinaryMeasurementConfig struct {
+	Rate             int // Hz
+	MeasurementTime  bool
+	RawMeasurements  bool
+	SVChannelStatus  bool
+	ReceiverNavState bool
+	Subframe         bool
+}
+
+// Output rates, in Hz, for binary measurements. The index of each rate is its code in the
+// configuration message.
+var measurementRates = []int{1, 2, 4, 5, 10, 20}
+
+// Time of the measurement on the GPS time scale.
+func (mt MeasurementTime) GPSTime() GPSTime {
+	return GPSTime{
+		Week:        mt.Week,
+		TimeOfWeek:  mt.TimeOfWeek,
+		LeapSeconds: mt.LeapSeconds,
+	}
+}
+
+func float32At(data []byte) float64 {
+	return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+}
+
+func float64At(data []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(data))
+}
+
+func (f *Frame) measurementTime() (MeasurementTime, error) {
+	const expectedLen = 9
+	if len(f.Data) != expectedLen {
+		logrus.WithField("length", len(f.Data)).
+			WithField("expectedLen", expectedLen).Error("expecting more data")
+		return MeasurementTime{}, errors.Errorf("measurementTime conversion requires %v bytes but received %v",
+			expectedLen, len(f.Data))
+	}
+	return MeasurementTime{
+		IOD:         int(f.Data[0]),
+		Week:        int(binary.BigEndian.Uint16(f.Data[1:3])),
+		TimeOfWeek:  time.Duration(binary.BigEndian.Uint32(f.Data[3:7])) * time.Millisecond,
+		Period:      time.Duration(binary.BigEndian.Uint16(f.Data[7:9])) * time.Millisecond,
+		LeapSeconds: DefaultLeapSeconds,
+	}, nil
+}
+
+func (f *Frame) rawMeasurements() (RawMeasurements, error) {
+	if len(f.Data) < 2 || len(f.Data) != 2+int(f.Data[1])*rawMeasurementSize {
+		logrus.WithField("length", len(f.Data)).Error("unexpected raw measurement length")
+		return RawMeasurements{}, errors.Errorf("rawMeasurements conversion received %v bytes", len(f.Data))
+	}
+
+	rm := RawMeasurements{
+		IOD:          int(f.Data[0]),
+		Measurements: make([]RawMeasurement, f.Data[1]),
+	}
+	for i := range rm.Measurements {
+		d := f.Data[2+i*rawMeasurementSize:]
+		rm.Measurements[i] = RawMeasurement{
+			SVID:         int(d[0]),
+			CN0:          int(d[1]),
+			Pseudorange:  float64At(d[2:10]),
+			CarrierPhase: float64At(d[10:18]),
+			Doppler:      float32At(d[18:22]),
+			Indicator:    MeasurementIndicator(d[22]),
+		}
+	}
+	return rm, nil
+}
+
+func (f *Frame) svChannelStatus() (SVChannelStatus, error) {
+	if len(f.Data) < 2 || len(f.Data) != 2+int(f.Data[1])*channelStatusSize {
+		logrus.WithField("length", len(f.Data)).Error("unexpected channel status length")
+		return SVChannelStatus{}, errors.Errorf("svChannelStatus conversion received %v bytes", len(f.Data))
+	}
+
+	cs := SVChannelStatus{
+		IOD:      int(f.Data[0]),
+		Channels: make([]ChannelStatus, f.Data[1]),
+	}
+	for i := range cs.Channels {
+		d := f.Data[2+i*channelStatusSize:]
+		cs.Channels[i] = ChannelStatus{
+			Channel:   int(d[0]),
+			SVID:      int(d[1]),
+			SVStatus:  SVStatus(d[2]),
+			URA:       int(d[3]),
+			CN0:       int(int8(d[4])),
+			Elevation: int(int16(binary.BigEndian.Uint16(d[5:7]))),
+			Azimuth:   int(int16(binary.BigEndian.Uint16(d[7:9]))),
+			Status:    ChannelStatusIndicator(d[9]),
+		}
+	}
+	return cs, nil
+}
+
+func (f *Frame) receiverNavState() (ReceiverNavState, error) {
+	const expectedLen = 80
+	if len(f.Data) != expectedLen {
+		logrus.WithField("length", len(f.Data)).
+			WithField("expectedLen", expectedLen).Error("expecting more data")
+		return ReceiverNavState{}, errors.Errorf("receiverNavState conversion requires %v bytes but received %v",
+			expectedLen, len(f.Data))
+	}
+	d := f.Data
+	return ReceiverNavState{
+		IOD:        int(d[0]),
+		State:      NavigationState(d[1]),
+		Week:       int(binary.BigEndian.Uint16(d[2:4])),
+		TimeOfWeek: float64At(d[4:12]),
+		Position:   ECEF{float64At(d[12:20]), float64At(d[20:28]), float64At(d[28:36])},
+		Velocity:   ECEF{float32At(d[36:40]), float32At(d[40:44]), float32At(d[44:48])},
+		ClockBias:  float64At(d[48:56]),
+		ClockDrift: float32At(d[56:60]),
+		GDOP:       float32At(d[60:64]),
+		PDOP:       float32At(d[64:68]),
+		HDOP:       float32At(d[68:72]),
+		VDOP:       float32At(d[72:76]),
+		TDOP:       float32At(d[76:80]),
+	}, nil
+}
+
+func (f *Frame) subframe() (Subframe, error) {
+	const expectedLen = 2 + subframeWordsSize
+	if len(f.Data) != expectedLen {
+		logrus.WithField("length", len(f.Data)).
+			WithField("expectedLen", expectedLen).Error("expecting more data")
+		return Subframe{}, errors.Errorf("subframe conversion requires %v bytes but received %v",
+			expectedLen, len(f.Data))
+	}
+	sf := Subframe{
+		SVID:       int(f.Data[0]),
+		SubframeID: int(f.Data[1]),
+	}
+	copy(sf.Words[:], f.Data[2:])
+	return sf, nil
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Configure which raw measurement messages the device outputs and at what rate.
+func (c *Connection) ConfigureBinaryMeasurement(ctx context.Context, config BinaryMeasurementConfig,
+	attr Attributes) error {
+	code := -1
+	for i, rate := range measurementRates {
+		if rate == config.Rate {
+			code = i
+			break
+		}
+	}
+	if code < 0 {
+		return errors.Errorf("unsupported measurement rate %v Hz", config.Rate)
+	}
+
+	if err := c.configure(ctx, &Frame{
+		ID: CommandConfigureBinaryMeasurement,
+		Data: []byte{
+			byte(code),
+			boolByte(config.MeasurementTime),
+			boolByte(config.RawMeasurements),
+			boolByte(config.SVChannelStatus),
+			boolByte(config.ReceiverNavState),
+			boolByte(config.Subframe),
+			byte(attr),
+		},
+	}); err != nil {
+		return errors.Wrapf(err, "unable to configure binary measurement output")
+	}
+	return nil
+}
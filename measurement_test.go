@@ -0,0 +1,180 @@
+package skytraq
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func rawMeasurementsData(rm RawMeasurements) []byte {
+	buf := bytes.Buffer{}
+	buf.Write([]byte{byte(rm.IOD), byte(len(rm.Measurements))})
+	for _, m := range rm.Measurements {
+		buf.Write([]byte{byte(m.SVID), byte(m.CN0)})
+		binary.Write(&buf, binary.BigEndian, m.Pseudorange)
+		binary.Write(&buf, binary.BigEndian, m.CarrierPhase)
+		binary.Write(&buf, binary.BigEndian, float32(m.Doppler))
+		buf.WriteByte(byte(m.Indicator))
+	}
+	return buf.Bytes()
+}
+
+func svChannelStatusData(cs SVChannelStatus) []byte {
+	buf := bytes.Buffer{}
+	buf.Write([]byte{byte(cs.IOD), byte(len(cs.Channels))})
+	for _, c := range cs.Channels {
+		buf.Write([]byte{byte(c.Channel), byte(c.SVID), byte(c.SVStatus), byte(c.URA), byte(c.CN0)})
+		binary.Write(&buf, binary.BigEndian, int16(c.Elevation))
+		binary.Write(&buf, binary.BigEndian, int16(c.Azimuth))
+		buf.WriteByte(byte(c.Status))
+	}
+	return buf.Bytes()
+}
+
+func receiverNavStateData(ns ReceiverNavState) []byte {
+	buf := bytes.Buffer{}
+	buf.Write([]byte{byte(ns.IOD), byte(ns.State)})
+	binary.Write(&buf, binary.BigEndian, uint16(ns.Week))
+	for _, v := range []float64{ns.TimeOfWeek, ns.Position.X, ns.Position.Y, ns.Position.Z} {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	for _, v := range []float64{ns.Velocity.X, ns.Velocity.Y, ns.Velocity.Z} {
+		binary.Write(&buf, binary.BigEndian, float32(v))
+	}
+	binary.Write(&buf, binary.BigEndian, ns.ClockBias)
+	for _, v := range []float64{ns.ClockDrift, ns.GDOP, ns.PDOP, ns.HDOP, ns.VDOP, ns.TDOP} {
+		binary.Write(&buf, binary.BigEndian, float32(v))
+	}
+	return buf.Bytes()
+}
+
+func TestMeasurementTime(t *testing.T) {
+	f := Frame{
+		ID:   ResponseMeasurementTime,
+		Data: []byte{7, 0x08, 0x98, 0x05, 0x26, 0x5c, 0x00, 0x00, 0xc8},
+	}
+	mt, err := f.measurementTime()
+	assert.NoError(t, err)
+	assert.Equal(t, MeasurementTime{
+		IOD:         7,
+		Week:        2200,
+		TimeOfWeek:  86400 * time.Second,
+		Period:      200 * time.Millisecond,
+		LeapSeconds: DefaultLeapSeconds,
+	}, mt)
+}
+
+func TestRawMeasurements(t *testing.T) {
+	expected := RawMeasurements{
+		IOD: 7,
+		Measurements: []RawMeasurement{
+			{SVID: 3, CN0: 45, Pseudorange: 21234567.891, CarrierPhase: -1234.5, Doppler: -1500.25,
+				Indicator: PseudorangeAvailable | DopplerAvailable | CarrierPhaseAvailable},
+			{SVID: 17, CN0: 30, Pseudorange: 23456789.125, Doppler: 250.5, Indicator: PseudorangeAvailable},
+		},
+	}
+	f := Frame{ID: ResponseRawMeasurements, Data: rawMeasurementsData(expected)}
+	assert.Equal(t, 48, len(f.Data))
+
+	rm, err := f.rawMeasurements()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, rm)
+
+	f.Data = f.Data[:47]
+	_, err = f.rawMeasurements()
+	assert.Error(t, err)
+}
+
+func TestSVChannelStatus(t *testing.T) {
+	expected := SVChannelStatus{
+		IOD: 7,
+		Channels: []ChannelStatus{
+			{Channel: 0, SVID: 3, SVStatus: SVAlmanacReceived | SVEphemerisReceived | SVHealthy, URA: 1,
+				CN0: 45, Elevation: 67, Azimuth: 296, Status: ChannelPullInDone | ChannelBitSync | ChannelUsedInFix},
+			{Channel: 1, SVID: 17, CN0: 20, Elevation: -2, Azimuth: 10},
+		},
+	}
+	f := Frame{ID: ResponseSVChannelStatus, Data: svChannelStatusData(expected)}
+	assert.Equal(t, 22, len(f.Data))
+
+	cs, err := f.svChannelStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, cs)
+}
+
+func TestReceiverNavState(t *testing.T) {
+	expected := ReceiverNavState{
+		IOD:        7,
+		State:      Navigation3D,
+		Week:       2200,
+		TimeOfWeek: 86400.25,
+		Position:   ECEF{-4646051.25, 2553206.5, -3534372.375},
+		Velocity:   ECEF{-2.5, 1.25, 0.5},
+		ClockBias:  123.5,
+		ClockDrift: -0.25,
+		GDOP:       2.5,
+		PDOP:       2,
+		HDOP:       1,
+		VDOP:       1.5,
+		TDOP:       1.25,
+	}
+	f := Frame{ID: ResponseReceiverNavState, Data: receiverNavStateData(expected)}
+	ns, err := f.receiverNavState()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, ns)
+}
+
+func TestSubframe(t *testing.T) {
+	data := []byte{12, 2}
+	for i := 0; i < subframeWordsSize; i++ {
+		data = append(data, byte(i))
+	}
+	f := Frame{ID: ResponseSubframe, Data: data}
+	sf, err := f.subframe()
+	assert.NoError(t, err)
+	assert.Equal(t, 12, sf.SVID)
+	assert.Equal(t, 2, sf.SubframeID)
+	assert.Equal(t, byte(29), sf.Words[29])
+}
+
+func TestStartMeasurements(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseMeasurementTime, []byte{1, 0, 1, 0, 0, 0, 1, 0, 1}, 0))
+	m.ReadBuf.Write(frameData(ResponseRawMeasurements, rawMeasurementsData(RawMeasurements{IOD: 1}), 0))
+	m.ReadBuf.Write(frameData(ResponseSVChannelStatus, svChannelStatusData(SVChannelStatus{IOD: 1}), 0))
+	m.ReadBuf.Write(frameData(ResponseReceiverNavState, receiverNavStateData(ReceiverNavState{IOD: 1}), 0))
+	m.ReadBuf.Write(frameData(ResponseSubframe, make([]byte, 32), 0))
+
+	counts := map[string]int{}
+	err := c.Start(context.Background(), Callbacks{
+		MeasurementTime:  func(MeasurementTime) { counts["time"]++ },
+		RawMeasurements:  func(RawMeasurements) { counts["raw"]++ },
+		SVChannelStatus:  func(SVChannelStatus) { counts["status"]++ },
+		ReceiverNavState: func(ReceiverNavState) { counts["state"]++ },
+		Subframe:         func(Subframe) { counts["subframe"]++ },
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, map[string]int{"time": 1, "raw": 1, "status": 1, "state": 1, "subframe": 1}, counts)
+}
+
+func TestConfigureBinaryMeasurement(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandConfigureBinaryMeasurement)}, 0))
+
+	assert.NoError(t, c.ConfigureBinaryMeasurement(context.Background(), BinaryMeasurementConfig{
+		Rate:            10,
+		MeasurementTime: true,
+		RawMeasurements: true,
+		Subframe:        true,
+	}, UpdateSRAM))
+	assert.Equal(t, frameData(CommandConfigureBinaryMeasurement, []byte{4, 1, 1, 0, 0, 1, 0}, 0),
+		m.WriteBuf.Bytes())
+
+	assert.EqualError(t, c.ConfigureBinaryMeasurement(context.Background(), BinaryMeasurementConfig{Rate: 8},
+		UpdateSRAM), "unsupported measurement rate 8 Hz")
+}
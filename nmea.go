@@ -0,0 +1,311 @@
+package skytraq
+
+import (
+	"github.com/pkg/errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// longest sentence accepted, excluding the leading '$' and the end of line
+const maxSentenceLength = 128
+
+// An NMEA 0183 sentence with a valid checksum.
+type Sentence struct {
+	Talker string // e.g. "GP", or "P" for proprietary sentences
+	Type   string // e.g. "GGA"
+	Fields []string
+}
+
+// GGA, GPS fix data.
+type GGA struct {
+	Time            time.Duration // since midnight UTC
+	Latitude        float64       // degrees, negative when south
+	Longitude       float64       // degrees, negative when west
+	Quality         int           // 0 when there is no fix
+	SatelliteCount  int
+	HDOP            float64
+	Altitude        float64 // meters above mean sea level
+	GeoidSeparation float64 // meters from the ellipsoid to mean sea level
+}
+
+// RMC, recommended minimum data.
+type RMC struct {
+	Time       time.Time // zero if not reported
+	Valid      bool
+	Latitude   float64
+	Longitude  float64
+	SpeedKnots float64
+	Course     float64 // degrees true
+}
+
+// GSA, dilution of precision and active satellites.
+type GSA struct {
+	Automatic bool // automatic rather than manual 2D/3D selection
+	FixType   int  // 1 no fix, 2 2D fix, 3 3D fix
+	PRNs      []int
+	PDOP      float64
+	HDOP      float64
+	VDOP      float64
+}
+
+type GSVSatellite struct {
+	PRN       int
+	Elevation int // degrees
+	Azimuth   int // degrees true
+	SNR       int // dB-Hz, 0 when not tracked
+}
+
+// GSV, satellites in view. Satellites are reported in groups of up to four over several sentences.
+type GSV struct {
+	Talker           string
+	SentenceCount    int
+	SentenceNumber   int
+	SatellitesInView int
+	Satellites       []GSVSatellite
+}
+
+// VTG, course and speed over ground.
+type VTG struct {
+	CourseTrue     float64
+	CourseMagnetic float64
+	SpeedKnots     float64
+	SpeedKPH       float64
+}
+
+// ZDA, UTC date and time with the local time zone.
+type ZDA struct {
+	Time        time.Time
+	ZoneHours   int
+	ZoneMinutes int
+}
+
+func nmeaChecksum(s string) byte {
+	var cs byte
+	for i := 0; i < len(s); i++ {
+		cs ^= s[i]
+	}
+	return cs
+}
+
+// Parse a sentence, excluding the leading '$' and end of line, validating its checksum.
+func parseSentence(line string) (*Sentence, error) {
+	star := strings.LastIndexByte(line, '*')
+	if star < 0 || star != len(line)-3 {
+		return nil, errors.Errorf("sentence %q has no checksum", line)
+	}
+	expected, err := strconv.ParseUint(line[star+1:], 16, 8)
+	if err != nil {
+		return nil, errors.Errorf("sentence %q has an invalid checksum", line)
+	}
+	body := line[:star]
+	if cs := nmeaChecksum(body); cs != byte(expected) {
+		return nil, errors.Errorf("expected checksum %02X but found %02X in sentence %q", cs, expected, line)
+	}
+
+	fields := strings.Split(body, ",")
+	address := fields[0]
+	s := &Sentence{
+		Fields: fields[1:],
+	}
+	switch {
+	case strings.HasPrefix(address, "P"):
+		s.Talker, s.Type = "P", address[1:]
+	case len(address) == 5:
+		s.Talker, s.Type = address[:2], address[2:]
+	default:
+		return nil, errors.Errorf("sentence %q has an invalid address", line)
+	}
+	return s, nil
+}
+
+func (s *Sentence) checkFields(expectedLen int) error {
+	if len(s.Fields) < expectedLen {
+		return errors.Errorf("%v conversion requires %v fields but received %v", s.Type, expectedLen,
+			len(s.Fields))
+	}
+	return nil
+}
+
+// Collects the first error from parsing a sentence's fields. Empty fields are parsed as zero.
+type fieldParser struct {
+	err error
+}
+
+func (p *fieldParser) float(field string) float64 {
+	if field == "" || p.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		p.err = errors.Wrapf(err, "invalid number")
+	}
+	return v
+}
+
+func (p *fieldParser) int(field string) int {
+	if field == "" || p.err != nil {
+		return 0
+	}
+	v, err := strconv.Atoi(field)
+	if err != nil {
+		p.err = errors.Wrapf(err, "invalid integer")
+	}
+	return v
+}
+
+// Parse ddmm.mmmm or dddmm.mmmm with its hemisphere into signed degrees.
+func (p *fieldParser) coordinate(field, hemisphere string) float64 {
+	v := p.float(field)
+	degrees := math.Trunc(v / 100)
+	degrees += (v - degrees*100) / 60
+	if hemisphere == "S" || hemisphere == "W" {
+		degrees = -degrees
+	}
+	return degrees
+}
+
+// Parse hhmmss.sss into the duration since midnight.
+func (p *fieldParser) timeOfDay(field string) time.Duration {
+	if field == "" || p.err != nil {
+		return 0
+	}
+	if len(field) < 6 {
+		p.err = errors.Errorf("invalid time %q", field)
+		return 0
+	}
+	hours := p.int(field[0:2])
+	minutes := p.int(field[2:4])
+	seconds := p.float(field[4:])
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(math.Round(seconds*1000))*time.Millisecond
+}
+
+func (p *fieldParser) date(day, month, year int, timeOfDay time.Duration) time.Time {
+	if p.err != nil || day == 0 {
+		return time.Time{}
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Add(timeOfDay)
+}
+
+func (s *Sentence) gga() (GGA, error) {
+	if err := s.checkFields(11); err != nil {
+		return GGA{}, err
+	}
+	f := s.Fields
+	p := fieldParser{}
+	gga := GGA{
+		Time:            p.timeOfDay(f[0]),
+		Latitude:        p.coordinate(f[1], f[2]),
+		Longitude:       p.coordinate(f[3], f[4]),
+		Quality:         p.int(f[5]),
+		SatelliteCount:  p.int(f[6]),
+		HDOP:            p.float(f[7]),
+		Altitude:        p.float(f[8]),
+		GeoidSeparation: p.float(f[10]),
+	}
+	return gga, errors.Wrapf(p.err, "unable to parse GGA")
+}
+
+func (s *Sentence) rmc() (RMC, error) {
+	if err := s.checkFields(9); err != nil {
+		return RMC{}, err
+	}
+	f := s.Fields
+	p := fieldParser{}
+	timeOfDay := p.timeOfDay(f[0])
+	var day, month, year int
+	if f[8] != "" {
+		if len(f[8]) != 6 {
+			return RMC{}, errors.Errorf("unable to parse RMC: invalid date %q", f[8])
+		}
+		day, month, year = p.int(f[8][0:2]), p.int(f[8][2:4]), 2000+p.int(f[8][4:6])
+	}
+	rmc := RMC{
+		Time:       p.date(day, month, year, timeOfDay),
+		Valid:      f[1] == "A",
+		Latitude:   p.coordinate(f[2], f[3]),
+		Longitude:  p.coordinate(f[4], f[5]),
+		SpeedKnots: p.float(f[6]),
+		Course:     p.float(f[7]),
+	}
+	return rmc, errors.Wrapf(p.err, "unable to parse RMC")
+}
+
+func (s *Sentence) gsa() (GSA, error) {
+	if err := s.checkFields(17); err != nil {
+		return GSA{}, err
+	}
+	f := s.Fields
+	p := fieldParser{}
+	gsa := GSA{
+		Automatic: f[0] == "A",
+		FixType:   p.int(f[1]),
+		PDOP:      p.float(f[14]),
+		HDOP:      p.float(f[15]),
+		VDOP:      p.float(f[16]),
+	}
+	for _, prn := range f[2:14] {
+		if prn != "" {
+			gsa.PRNs = append(gsa.PRNs, p.int(prn))
+		}
+	}
+	return gsa, errors.Wrapf(p.err, "unable to parse GSA")
+}
+
+func (s *Sentence) gsv() (GSV, error) {
+	if err := s.checkFields(3); err != nil {
+		return GSV{}, err
+	}
+	f := s.Fields
+	p := fieldParser{}
+	gsv := GSV{
+		Talker:           s.Talker,
+		SentenceCount:    p.int(f[0]),
+		SentenceNumber:   p.int(f[1]),
+		SatellitesInView: p.int(f[2]),
+	}
+	for i := 3; i+3 < len(f); i += 4 {
+		if f[i] == "" {
+			continue
+		}
+		gsv.Satellites = append(gsv.Satellites, GSVSatellite{
+			PRN:       p.int(f[i]),
+			Elevation: p.int(f[i+1]),
+			Azimuth:   p.int(f[i+2]),
+			SNR:       p.int(f[i+3]),
+		})
+	}
+	return gsv, errors.Wrapf(p.err, "unable to parse GSV")
+}
+
+func (s *Sentence) vtg() (VTG, error) {
+	if err := s.checkFields(8); err != nil {
+		return VTG{}, err
+	}
+	f := s.Fields
+	p := fieldParser{}
+	vtg := VTG{
+		CourseTrue:     p.float(f[0]),
+		CourseMagnetic: p.float(f[2]),
+		SpeedKnots:     p.float(f[4]),
+		SpeedKPH:       p.float(f[6]),
+	}
+	return vtg, errors.Wrapf(p.err, "unable to parse VTG")
+}
+
+func (s *Sentence) zda() (ZDA, error) {
+	if err := s.checkFields(6); err != nil {
+		return ZDA{}, err
+	}
+	f := s.Fields
+	p := fieldParser{}
+	timeOfDay := p.timeOfDay(f[0])
+	zda := ZDA{
+		Time:        p.date(p.int(f[1]), p.int(f[2]), p.int(f[3]), timeOfDay),
+		ZoneHours:   p.int(f[4]),
+		ZoneMinutes: p.int(f[5]),
+	}
+	return zda, errors.Wrapf(p.err, "unable to parse ZDA")
+}
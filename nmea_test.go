@@ -0,0 +1,192 @@
+package skytraq
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+// complete sentence with checksum and end of line
+func nmeaSentence(body string) string {
+	return fmt.Sprintf("$%s*%02X\r\n", body, nmeaChecksum(body))
+}
+
+func TestParseSentence(t *testing.T) {
+	s, err := parseSentence("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47")
+	assert.NoError(t, err)
+	assert.Equal(t, "GP", s.Talker)
+	assert.Equal(t, "GGA", s.Type)
+	assert.Equal(t, 14, len(s.Fields))
+	assert.Equal(t, "123519", s.Fields[0])
+
+	s, err = parseSentence("PSTI,001,1*" + fmt.Sprintf("%02X", nmeaChecksum("PSTI,001,1")))
+	assert.NoError(t, err)
+	assert.Equal(t, "P", s.Talker)
+	assert.Equal(t, "STI", s.Type)
+}
+
+func TestParseSentenceInvalid(t *testing.T) {
+	for _, line := range []string{
+		"GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48",
+		"GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,",
+		"GPGGA,123519*4",
+		"GPGGA,123519*ZZ",
+		"GGA*" + fmt.Sprintf("%02X", nmeaChecksum("GGA")),
+	} {
+		_, err := parseSentence(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func sentence(t *testing.T, body string) *Sentence {
+	s, err := parseSentence(body + fmt.Sprintf("*%02X", nmeaChecksum(body)))
+	assert.NoError(t, err)
+	return s
+}
+
+func TestGGA(t *testing.T) {
+	gga, err := sentence(t, "GPGGA,123519.50,4807.038,N,01131.000,W,1,08,0.9,545.4,M,46.9,M,,").gga()
+	assert.NoError(t, err)
+	assert.Equal(t, 12*time.Hour+35*time.Minute+19500*time.Millisecond, gga.Time)
+	assert.InDelta(t, 48.1173, gga.Latitude, 1e-9)
+	assert.InDelta(t, -11.516666667, gga.Longitude, 1e-9)
+	assert.Equal(t, 1, gga.Quality)
+	assert.Equal(t, 8, gga.SatelliteCount)
+	assert.InDelta(t, 0.9, gga.HDOP, 1e-9)
+	assert.InDelta(t, 545.4, gga.Altitude, 1e-9)
+	assert.InDelta(t, 46.9, gga.GeoidSeparation, 1e-9)
+
+	gga, err = sentence(t, "GPGGA,,,,,,0,00,,,M,,M,,").gga()
+	assert.NoError(t, err)
+	assert.Equal(t, GGA{}, gga)
+
+	_, err = sentence(t, "GPGGA,123519,48x7.038,N,01131.000,W,1,08,0.9,545.4,M,46.9,M,,").gga()
+	assert.Error(t, err)
+
+	_, err = sentence(t, "GPGGA,123519").gga()
+	assert.EqualError(t, err, "GGA conversion requires 11 fields but received 1")
+}
+
+func TestRMC(t *testing.T) {
+	rmc, err := sentence(t, "GPRMC,225446,A,3352.128,S,15112.558,E,000.5,054.7,191124,020.3,E").rmc()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.November, 19, 22, 54, 46, 0, time.UTC), rmc.Time)
+	assert.True(t, rmc.Valid)
+	assert.InDelta(t, -33.8688, rmc.Latitude, 1e-9)
+	assert.InDelta(t, 151.2093, rmc.Longitude, 1e-9)
+	assert.InDelta(t, 0.5, rmc.SpeedKnots, 1e-9)
+	assert.InDelta(t, 54.7, rmc.Course, 1e-9)
+
+	rmc, err = sentence(t, "GPRMC,,V,,,,,,,,,,N").rmc()
+	assert.NoError(t, err)
+	assert.Equal(t, RMC{}, rmc)
+}
+
+func TestGSA(t *testing.T) {
+	gsa, err := sentence(t, "GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1").gsa()
+	assert.NoError(t, err)
+	assert.Equal(t, GSA{
+		Automatic: true,
+		FixType:   3,
+		PRNs:      []int{4, 5, 9, 12, 24},
+		PDOP:      2.5,
+		HDOP:      1.3,
+		VDOP:      2.1,
+	}, gsa)
+}
+
+func TestGSV(t *testing.T) {
+	gsv, err := sentence(t, "GPGSV,3,2,11,14,25,170,00,16,57,208,39,18,67,296,40,19,40,246,").gsv()
+	assert.NoError(t, err)
+	assert.Equal(t, GSV{
+		Talker:           "GP",
+		SentenceCount:    3,
+		SentenceNumber:   2,
+		SatellitesInView: 11,
+		Satellites: []GSVSatellite{
+			{PRN: 14, Elevation: 25, Azimuth: 170, SNR: 0},
+			{PRN: 16, Elevation: 57, Azimuth: 208, SNR: 39},
+			{PRN: 18, Elevation: 67, Azimuth: 296, SNR: 40},
+			{PRN: 19, Elevation: 40, Azimuth: 246, SNR: 0},
+		},
+	}, gsv)
+
+	gsv, err = sentence(t, "GLGSV,1,1,01,65,10,020,30").gsv()
+	assert.NoError(t, err)
+	assert.Equal(t, "GL", gsv.Talker)
+	assert.Equal(t, []GSVSatellite{{PRN: 65, Elevation: 10, Azimuth: 20, SNR: 30}}, gsv.Satellites)
+}
+
+func TestVTG(t *testing.T) {
+	vtg, err := sentence(t, "GPVTG,054.7,T,034.4,M,005.5,N,010.2,K,A").vtg()
+	assert.NoError(t, err)
+	assert.Equal(t, VTG{
+		CourseTrue:     54.7,
+		CourseMagnetic: 34.4,
+		SpeedKnots:     5.5,
+		SpeedKPH:       10.2,
+	}, vtg)
+}
+
+func TestZDA(t *testing.T) {
+	zda, err := sentence(t, "GPZDA,201530.00,04,07,2002,-05,30").zda()
+	assert.NoError(t, err)
+	assert.Equal(t, ZDA{
+		Time:        time.Date(2002, time.July, 4, 20, 15, 30, 0, time.UTC),
+		ZoneHours:   -5,
+		ZoneMinutes: 30,
+	}, zda)
+}
+
+func TestStartMixedStream(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.WriteString(nmeaSentence("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"))
+	m.ReadBuf.Write(frameData(ResponseNavData, navData, 0))
+	m.ReadBuf.WriteString(nmeaSentence("GPRMC,225446,A,4916.45,N,12311.12,W,000.5,054.7,191194,020.3,E"))
+	// bad checksum is skipped
+	m.ReadBuf.WriteString("$GPGGA,,,,,,0,00,,,M,,M,,*67\r\n")
+	// truncated sentence followed by a frame
+	m.ReadBuf.WriteString("$GPGSA,A,3,04,0")
+	m.ReadBuf.Write(frameData(ResponseNavData, navData, 0))
+	m.ReadBuf.WriteString(nmeaSentence("GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1"))
+	m.ReadBuf.WriteString(nmeaSentence("GPGSV,1,1,01,14,25,170,00"))
+	m.ReadBuf.WriteString(nmeaSentence("GPVTG,054.7,T,034.4,M,005.5,N,010.2,K,A"))
+	m.ReadBuf.WriteString(nmeaSentence("GPZDA,201530.00,04,07,2002,00,00"))
+	m.ReadBuf.WriteString(nmeaSentence("GPGLL,4916.45,N,12311.12,W,225444,A"))
+
+	counts := map[string]int{}
+	err := c.Start(context.Background(), Callbacks{
+		NavData: func(NavData) { counts["nav"]++ },
+		GGA:     func(GGA) { counts["GGA"]++ },
+		RMC:     func(RMC) { counts["RMC"]++ },
+		GSA:     func(GSA) { counts["GSA"]++ },
+		GSV:     func(GSV) { counts["GSV"]++ },
+		VTG:     func(VTG) { counts["VTG"]++ },
+		ZDA:     func(ZDA) { counts["ZDA"]++ },
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, map[string]int{
+		"nav": 2,
+		"GGA": 1,
+		"RMC": 1,
+		"GSA": 1,
+		"GSV": 1,
+		"VTG": 1,
+		"ZDA": 1,
+	}, counts)
+}
+
+func TestReadFrameSkipsSentences(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.WriteString(nmeaSentence("GPGGA,,,,,,0,00,,,M,,M,,"))
+	m.ReadBuf.WriteString(nmeaSentence("GPGSV,1,1,00"))
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{2}, 0))
+
+	f, err := c.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, ResponseACK, f.ID)
+}
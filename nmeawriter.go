@@ -0,0 +1,244 @@
+package skytraq
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// talker ID used for generated sentences
+const nmeaTalkerGPS = "GP"
+
+// maximum number of satellites listed in a GSA sentence
+const gsaMaxPRNs = 12
+
+// Writes NMEA sentences to an io.Writer, allowing a device in binary mode to feed NMEA consumers.
+type NMEAWriter struct {
+	w io.Writer
+}
+
+func NewNMEAWriter(w io.Writer) *NMEAWriter {
+	return &NMEAWriter{w: w}
+}
+
+// Write a sentence followed by the end of line.
+func (w *NMEAWriter) WriteSentence(s *Sentence) error {
+	_, err := io.WriteString(w.w, s.String()+"\r\n")
+	return err
+}
+
+// Write GGA, RMC, VTG and GSA sentences for the navigation data. prns lists the satellites used in
+// the fix, when known, for the GSA sentence.
+func (w *NMEAWriter) WriteNavData(nd NavData, prns ...int) error {
+	for _, s := range []*Sentence{
+		nd.GGA().Sentence(),
+		nd.RMC().Sentence(),
+		nd.VTG().Sentence(),
+		nd.GSA(prns...).Sentence(),
+	} {
+		if err := w.WriteSentence(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The sentence as it is sent, excluding the end of line.
+func (s *Sentence) String() string {
+	body := strings.Join(append([]string{s.Talker + s.Type}, s.Fields...), ",")
+	return fmt.Sprintf("$%s*%02X", body, nmeaChecksum(body))
+}
+
+// GGA sentence for the navigation data.
+func (nd NavData) GGA() GGA {
+	quality := 0
+	switch nd.Fix {
+	case Fix2D, Fix3D:
+		quality = 1
+	case Fix3DAndDGNSS:
+		quality = 2
+	}
+	utc := nd.Time.UTC()
+	return GGA{
+		Time:            utc.Sub(utc.Truncate(24 * time.Hour)),
+		Latitude:        nd.LatitudeDegrees(),
+		Longitude:       nd.LongitudeDegrees(),
+		Quality:         quality,
+		SatelliteCount:  nd.SatelliteCount,
+		HDOP:            nd.HDOPValue(),
+		Altitude:        nd.AltitudeMeters(),
+		GeoidSeparation: nd.EllipsoidAltitudeMeters() - nd.AltitudeMeters(),
+	}
+}
+
+// RMC sentence for the navigation data.
+func (nd NavData) RMC() RMC {
+	v := nd.VelocityENU()
+	return RMC{
+		Time:       nd.Time.UTC(),
+		Valid:      nd.Fix != FixNone,
+		Latitude:   nd.LatitudeDegrees(),
+		Longitude:  nd.LongitudeDegrees(),
+		SpeedKnots: v.GroundSpeedKnots(),
+		Course:     v.Course(),
+	}
+}
+
+// VTG sentence for the navigation data. The magnetic course is not known.
+func (nd NavData) VTG() VTG {
+	v := nd.VelocityENU()
+	return VTG{
+		CourseTrue: v.Course(),
+		SpeedKnots: v.GroundSpeedKnots(),
+		SpeedKPH:   v.GroundSpeedKPH(),
+	}
+}
+
+// GSA sentence for the navigation data, listing the supplied satellites as used in the fix.
+func (nd NavData) GSA(prns ...int) GSA {
+	fixType := 1
+	switch nd.Fix {
+	case Fix2D:
+		fixType = 2
+	case Fix3D, Fix3DAndDGNSS:
+		fixType = 3
+	}
+	return GSA{
+		Automatic: true,
+		FixType:   fixType,
+		PRNs:      prns,
+		PDOP:      nd.PDOPValue(),
+		HDOP:      nd.HDOPValue(),
+		VDOP:      nd.VDOPValue(),
+	}
+}
+
+// Position fields are left empty when there is no fix.
+func (g GGA) Sentence() *Sentence {
+	lat, ns, lon, ew := "", "", "", ""
+	if g.Quality != 0 {
+		lat, ns, lon, ew = formatCoordinates(g.Latitude, g.Longitude)
+	}
+	return &Sentence{
+		Talker: nmeaTalkerGPS,
+		Type:   "GGA",
+		Fields: []string{
+			formatTimeOfDay(g.Time),
+			lat, ns, lon, ew,
+			strconv.Itoa(g.Quality),
+			fmt.Sprintf("%02d", g.SatelliteCount),
+			formatFloat(g.HDOP, 2),
+			formatFloat(g.Altitude, 1), "M",
+			formatFloat(g.GeoidSeparation, 1), "M",
+			"", "",
+		},
+	}
+}
+
+// Position fields are left empty when the data is not valid, and the date is left empty when the
+// time is zero.
+func (r RMC) Sentence() *Sentence {
+	status, mode := "V", "N"
+	lat, ns, lon, ew := "", "", "", ""
+	if r.Valid {
+		status, mode = "A", "A"
+		lat, ns, lon, ew = formatCoordinates(r.Latitude, r.Longitude)
+	}
+	timeOfDay, date := "", ""
+	if !r.Time.IsZero() {
+		utc := r.Time.UTC()
+		timeOfDay = formatTimeOfDay(utc.Sub(utc.Truncate(24 * time.Hour)))
+		date = utc.Format("020106")
+	}
+	return &Sentence{
+		Talker: nmeaTalkerGPS,
+		Type:   "RMC",
+		Fields: []string{
+			timeOfDay,
+			status,
+			lat, ns, lon, ew,
+			formatFloat(r.SpeedKnots, 2),
+			formatFloat(r.Course, 2),
+			date,
+			"", "",
+			mode,
+		},
+	}
+}
+
+// The magnetic course is left empty when it is zero.
+func (v VTG) Sentence() *Sentence {
+	magnetic := ""
+	if v.CourseMagnetic != 0 {
+		magnetic = formatFloat(v.CourseMagnetic, 2)
+	}
+	return &Sentence{
+		Talker: nmeaTalkerGPS,
+		Type:   "VTG",
+		Fields: []string{
+			formatFloat(v.CourseTrue, 2), "T",
+			magnetic, "M",
+			formatFloat(v.SpeedKnots, 2), "N",
+			formatFloat(v.SpeedKPH, 2), "K",
+			"A",
+		},
+	}
+}
+
+// Only the first 12 satellites are listed.
+func (g GSA) Sentence() *Sentence {
+	selection := "M"
+	if g.Automatic {
+		selection = "A"
+	}
+	fields := []string{selection, strconv.Itoa(g.FixType)}
+	for i := 0; i < gsaMaxPRNs; i++ {
+		prn := ""
+		if i < len(g.PRNs) {
+			prn = fmt.Sprintf("%02d", g.PRNs[i])
+		}
+		fields = append(fields, prn)
+	}
+	fields = append(fields, formatFloat(g.PDOP, 2), formatFloat(g.HDOP, 2), formatFloat(g.VDOP, 2))
+	return &Sentence{
+		Talker: nmeaTalkerGPS,
+		Type:   "GSA",
+		Fields: fields,
+	}
+}
+
+func formatFloat(v float64, decimals int) string {
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// Format a duration since midnight as hhmmss.ss.
+func formatTimeOfDay(d time.Duration) string {
+	cs := int64(math.Round(float64(d) / float64(10*time.Millisecond)))
+	return fmt.Sprintf("%02d%02d%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// Format degrees as ddmm.mmmmm for latitude and dddmm.mmmmm for longitude with their hemispheres.
+func formatCoordinates(lat, lon float64) (string, string, string, string) {
+	ns, ew := "N", "E"
+	if lat < 0 {
+		ns = "S"
+	}
+	if lon < 0 {
+		ew = "W"
+	}
+	return formatCoordinate(lat, 2), ns, formatCoordinate(lon, 3), ew
+}
+
+func formatCoordinate(degrees float64, width int) string {
+	degrees = math.Abs(degrees)
+	whole := math.Floor(degrees)
+	minutes := math.Round((degrees-whole)*60*1e5) / 1e5
+	if minutes >= 60 {
+		whole++
+		minutes -= 60
+	}
+	return fmt.Sprintf("%0*d%08.5f", width, int(whole), minutes)
+}
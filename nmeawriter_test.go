@@ -0,0 +1,115 @@
+package skytraq
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatCoordinate(t *testing.T) {
+	assert.Equal(t, "4807.03800", formatCoordinate(48.1173, 2))
+	assert.Equal(t, "01131.00000", formatCoordinate(-11.516666667, 3))
+	assert.Equal(t, "0000.00000", formatCoordinate(0, 2))
+	assert.Equal(t, "18000.00000", formatCoordinate(179.9999999999, 3))
+}
+
+func TestFormatTimeOfDay(t *testing.T) {
+	assert.Equal(t, "000000.00", formatTimeOfDay(0))
+	assert.Equal(t, "123519.50", formatTimeOfDay(12*time.Hour+35*time.Minute+19500*time.Millisecond))
+	assert.Equal(t, "235959.99", formatTimeOfDay(24*time.Hour-10*time.Millisecond))
+}
+
+func TestSentenceString(t *testing.T) {
+	s := &Sentence{
+		Talker: "GP",
+		Type:   "GGA",
+		Fields: strings.Split("123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,", ","),
+	}
+	assert.Equal(t, "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47", s.String())
+}
+
+// NavData in Sydney on 2022-03-07 at 11:59:43.5 UTC heading north east at 10 m/s
+func sydneyNavData() NavData {
+	return NavData{
+		Fix:               Fix3D,
+		SatelliteCount:    9,
+		Latitude:          -338688000,
+		Longitude:         1512093000,
+		EllipsoidAltitude: 4650,
+		Altitude:          2400,
+		PDOP:              180,
+		HDOP:              95,
+		VDOP:              150,
+		VX:                -299,
+		VY:                -1048,
+		VZ:                587,
+		Time: GPSTime{
+			Week:        2200,
+			TimeOfWeek:  36*time.Hour + 1500*time.Millisecond,
+			LeapSeconds: 18,
+		},
+	}
+}
+
+func TestNavDataSentences(t *testing.T) {
+	nd := sydneyNavData()
+
+	gga := nd.GGA().Sentence()
+	assert.Equal(t, "$GPGGA,115943.50,3352.12800,S,15112.55800,E,1,09,0.95,24.0,M,22.5,M,,*7B", gga.String())
+	parsedGGA, err := gga.gga()
+	assert.NoError(t, err)
+	assert.InDelta(t, nd.LatitudeDegrees(), parsedGGA.Latitude, 1e-7)
+	assert.InDelta(t, nd.LongitudeDegrees(), parsedGGA.Longitude, 1e-7)
+
+	rmc := nd.RMC().Sentence()
+	assert.Equal(t, "GPRMC", rmc.Talker+rmc.Type)
+	parsedRMC, err := rmc.rmc()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, time.March, 7, 11, 59, 43, 500000000, time.UTC), parsedRMC.Time)
+	assert.True(t, parsedRMC.Valid)
+	assert.InDelta(t, nd.VelocityENU().GroundSpeedKnots(), parsedRMC.SpeedKnots, 0.005)
+	assert.InDelta(t, nd.VelocityENU().Course(), parsedRMC.Course, 0.005)
+
+	vtg := nd.VTG().Sentence()
+	assert.Equal(t, "", vtg.Fields[2])
+	parsedVTG, err := vtg.vtg()
+	assert.NoError(t, err)
+	assert.InDelta(t, nd.VelocityENU().GroundSpeedKPH(), parsedVTG.SpeedKPH, 0.005)
+
+	gsa := nd.GSA(4, 5, 9).Sentence()
+	assert.Equal(t, "$GPGSA,A,3,04,05,09,,,,,,,,,,1.80,0.95,1.50*0B", gsa.String())
+	parsedGSA, err := gsa.gsa()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{4, 5, 9}, parsedGSA.PRNs)
+}
+
+func TestNavDataSentencesNoFix(t *testing.T) {
+	nd := NavData{Latitude: 10, Longitude: 10, Time: GPSTime{Week: 2200}}
+	assert.Equal(t, "$GPGGA,000000.00,,,,,0,00,0.00,0.0,M,0.0,M,,*56", nd.GGA().Sentence().String())
+
+	rmc := nd.RMC().Sentence()
+	assert.Equal(t, "V", rmc.Fields[1])
+	assert.Equal(t, "", rmc.Fields[2])
+	assert.Equal(t, "N", rmc.Fields[11])
+	assert.Equal(t, "1", nd.GSA().Sentence().Fields[1])
+}
+
+func TestNMEAWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewNMEAWriter(&buf)
+	assert.NoError(t, w.WriteNavData(sydneyNavData(), 4, 5, 9))
+
+	// written sentences are read back from a mixed stream
+	c, m := connection()
+	m.ReadBuf.Write(buf.Bytes())
+	var types []string
+	for i := 0; i < 4; i++ {
+		_, s, err := c.readMessage()
+		assert.NoError(t, err)
+		types = append(types, s.Type)
+	}
+	assert.Equal(t, []string{"GGA", "RMC", "VTG", "GSA"}, types)
+	assert.True(t, strings.HasSuffix(buf.String(), "\r\n"))
+}
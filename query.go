@@ -0,0 +1,104 @@
+package skytraq
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// software type used by the version and CRC queries, 1 is the system code
+const softwareTypeSystemCode = 1
+
+// Send a command frame and then read frames until one with the supplied response ID arrives. Frames
+// of other types are ignored, as devices will often be sending periodic data while waiting. The
+// returned frame shares the connection's buffer and is only valid until the next read.
+//
+// Queries read directly from the connection and must not be used while Start is running.
+func (c *Connection) query(ctx context.Context, cmd *Frame, respID MessageID) (*Frame, error) {
+	return c.queryFunc(ctx, cmd, func(f *Frame) bool {
+		return f.ID == respID
+	})
+}
+
+// As query, but the response is the first frame for which match returns true.
+func (c *Connection) queryFunc(ctx context.Context, cmd *Frame, match func(*Frame) bool) (*Frame, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := c.WriteFrame(cmd); err != nil {
+		return nil, errors.Wrapf(err, "unable to send query %v", cmd.ID)
+	}
+	f, err := c.readResponse(ctx, match)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error when reading response to query %v", cmd.ID)
+	}
+	return f, nil
+}
+
+// Read frames until one for which match returns true, or until ctx is done.
+func (c *Connection) readResponse(ctx context.Context, match func(*Frame) bool) (*Frame, error) {
+	for {
+		f, err := c.ReadFrame()
+		if err != nil {
+			return nil, err
+		}
+		if match(f) {
+			return f, nil
+		}
+		logrus.WithField("messageID", f.ID).Debug("ignoring frame while waiting for query response")
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+	}
+}
+
+// Query the software version of the device.
+func (c *Connection) QuerySoftwareVersion(ctx context.Context) (SoftwareVersion, error) {
+	f, err := c.query(ctx, &Frame{
+		ID:   CommandQuerySoftwareVersion,
+		Data: []byte{softwareTypeSystemCode},
+	}, ResponseSoftwareVersion)
+	if err != nil {
+		return SoftwareVersion{}, err
+	}
+	return f.softwareVersion()
+}
+
+// Query the CRC of the device's system code.
+func (c *Connection) QuerySoftwareCRC(ctx context.Context) (SoftwareCRC, error) {
+	f, err := c.query(ctx, &Frame{
+		ID:   CommandQuerySoftwareCRC,
+		Data: []byte{softwareTypeSystemCode},
+	}, ResponseSoftwareCRC)
+	if err != nil {
+		return 0, err
+	}
+	return f.softwareCRC()
+}
+
+// Query the rate, in Hz, at which the device updates its position.
+func (c *Connection) QueryPositionRate(ctx context.Context) (PositionRate, error) {
+	f, err := c.query(ctx, &Frame{
+		ID:   CommandQueryPositionRate,
+		Data: []byte{},
+	}, ResponsePositionRate)
+	if err != nil {
+		return 0, err
+	}
+	return f.positionRate()
+}
+
+// Query the power mode of the device.
+func (c *Connection) QueryPowerMode(ctx context.Context) (PowerMode, error) {
+	f, err := c.query(ctx, &Frame{
+		ID:   CommandQueryPowerMode,
+		Data: []byte{},
+	}, ResponsePowerMode)
+	if err != nil {
+		return 0, err
+	}
+	return f.powerMode()
+}
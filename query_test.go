@@ -0,0 +1,71 @@
+package skytraq
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestQuerySoftwareVersion(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+	m.ReadBuf.Write(frameData(ResponseNavData, navData, 0))
+	m.ReadBuf.Write(frameData(ResponseSoftwareVersion, versionData, 0))
+
+	version, err := c.QuerySoftwareVersion(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Version{1, 2, 3}, version.Kernel)
+	assert.Equal(t, Version{4, 5, 6}, version.ODM)
+	assert.Equal(t, Version{2007, 8, 9}, version.Revision)
+	assert.Equal(t, frameData(CommandQuerySoftwareVersion, []byte{1}, 0), m.WriteBuf.Bytes())
+}
+
+func TestQuerySoftwareCRC(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareCRC)}, 0))
+	m.ReadBuf.Write(frameData(ResponseSoftwareCRC, []byte{1, 0x12, 0x34}, 0))
+
+	crc, err := c.QuerySoftwareCRC(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, SoftwareCRC(0x1234), crc)
+}
+
+func TestQueryPositionRate(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQueryPositionRate)}, 0))
+	m.ReadBuf.Write(frameData(ResponsePositionRate, []byte{10}, 0))
+
+	rate, err := c.QueryPositionRate(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, PositionRate(10), rate)
+}
+
+func TestQueryPowerMode(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQueryPowerMode)}, 0))
+	m.ReadBuf.Write(frameData(ResponsePowerMode, []byte{1}, 0))
+
+	mode, err := c.QueryPowerMode(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, PowerSave, mode)
+}
+
+func TestQueryNoResponse(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQueryPowerMode)}, 0))
+
+	_, err := c.QueryPowerMode(context.Background())
+	assert.Error(t, err)
+}
+
+func TestQueryCancelled(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQueryPowerMode)}, 0))
+	m.ReadBuf.Write(frameData(ResponsePowerMode, []byte{1}, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.QueryPowerMode(ctx)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, m.WriteBuf.Len())
+}
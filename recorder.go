@@ -0,0 +1,175 @@
+package skytraq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Capture files start with captureMagic, the format version and the wall clock time the capture
+// started. Each record that follows is the time since the start of the capture, the direction,
+// the length of the data and the data as it was sent on the wire.
+const (
+	captureMagic      = "STQC"
+	captureVersion    = 1
+	captureHeaderSize = 14
+	recordHeaderSize  = 13
+)
+
+// Direction of recorded data, relative to the host.
+type Direction uint8
+
+const (
+	DirectionRead  Direction = 0
+	DirectionWrite Direction = 1
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionRead:
+		return "read"
+	case DirectionWrite:
+		return "write"
+	}
+	return "unknown"
+}
+
+// Data read from or written to the port in a capture file.
+type Record struct {
+	Time      time.Duration // since the capture started, from the host's monotonic clock
+	Direction Direction
+	Data      []byte // as returned by a single read from, or passed to a single write to, the port
+}
+
+// Writes every byte read from or written to a device's port to a capture file, as it was read or
+// written, so that a session can be replayed exactly. Errors writing the capture are logged rather
+// than interrupting the connection and are returned by Close.
+type Recorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	start  time.Time
+	err    error
+}
+
+// Start a capture written to w.
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	r := &Recorder{
+		w:     w,
+		start: timeNow(),
+	}
+	header := make([]byte, captureHeaderSize)
+	copy(header, captureMagic)
+	binary.BigEndian.PutUint16(header[4:6], captureVersion)
+	binary.BigEndian.PutUint64(header[6:14], uint64(r.start.UnixNano()))
+	if _, err := w.Write(header); err != nil {
+		return nil, errors.Wrapf(err, "unable to write capture header")
+	}
+	return r, nil
+}
+
+// Start a capture written to a new file at path, replacing any existing file.
+func CreateRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create capture file")
+	}
+	r, err := NewRecorder(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// Record data sent in direction d. Each record is written with a single write so that a capture
+// cut short by a crash or power loss is only missing whole records.
+func (r *Recorder) Record(d Direction, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint64(buf[0:8], uint64(timeNow().Sub(r.start)))
+	buf[8] = byte(d)
+	binary.BigEndian.PutUint32(buf[9:13], uint32(len(data)))
+	buf = append(buf, data...)
+	if _, err := r.w.Write(buf); err != nil {
+		r.err = errors.Wrapf(err, "unable to write capture record")
+		logrus.Warn(r.err)
+	}
+}
+
+// Stop recording, closing the capture file if the recorder created it.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.err
+	if r.err == nil {
+		r.err = errors.New("recorder closed")
+	}
+	if r.closer != nil {
+		if closeErr := r.closer.Close(); err == nil && closeErr != nil {
+			err = errors.Wrapf(closeErr, "unable to close capture file")
+		}
+		r.closer = nil
+	}
+	return err
+}
+
+// Reads the records of a capture file in order.
+type CaptureReader struct {
+	r       io.Reader
+	Started time.Time // wall clock time the capture started
+}
+
+// Read the header of a capture from r.
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	header := make([]byte, captureHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrapf(err, "unable to read capture header")
+	}
+	if !bytes.Equal(header[:4], []byte(captureMagic)) {
+		return nil, errors.New("not a capture file")
+	}
+	if version := binary.BigEndian.Uint16(header[4:6]); version != captureVersion {
+		return nil, errors.Errorf("unsupported capture version %v", version)
+	}
+	return &CaptureReader{
+		r:       r,
+		Started: time.Unix(0, int64(binary.BigEndian.Uint64(header[6:14]))),
+	}, nil
+}
+
+// Read the next record, returning io.EOF at the end of the capture. A record cut short is
+// treated as the end of the capture.
+func (cr *CaptureReader) Next() (Record, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(cr.r, header); err != nil {
+		return Record{}, recordError(err)
+	}
+	rec := Record{
+		Time:      time.Duration(binary.BigEndian.Uint64(header[0:8])),
+		Direction: Direction(header[8]),
+		Data:      make([]byte, binary.BigEndian.Uint32(header[9:13])),
+	}
+	if _, err := io.ReadFull(cr.r, rec.Data); err != nil {
+		return Record{}, recordError(err)
+	}
+	return rec, nil
+}
+
+func recordError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return io.EOF
+	}
+	return errors.Wrapf(err, "unable to read capture record")
+}
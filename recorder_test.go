@@ -0,0 +1,206 @@
+package skytraq
+
+import (
+	"bytes"
+	"github.com/jd3nn1s/serial"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var captureStart = time.Date(2024, 11, 19, 8, 30, 0, 0, time.UTC)
+
+// clock that advances by step each time it is read, starting at captureStart
+func steppingClock(step time.Duration) func() {
+	oldTimeNow := timeNow
+	now := captureStart.Add(-step)
+	timeNow = func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+	return func() {
+		timeNow = oldTimeNow
+	}
+}
+
+type failingWriter struct {
+	remaining int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.remaining < len(p) {
+		return 0, errors.New("disk full")
+	}
+	w.remaining -= len(p)
+	return len(p), nil
+}
+
+func TestRecorder(t *testing.T) {
+	defer steppingClock(10 * time.Millisecond)()
+
+	buf := bytes.Buffer{}
+	r, err := NewRecorder(&buf)
+	assert.NoError(t, err)
+	r.Record(DirectionWrite, []byte{1, 2, 3})
+	r.Record(DirectionRead, []byte("$GPGSV,1,1,00*79\r\n"))
+	assert.NoError(t, r.Close())
+
+	// records after closing are dropped
+	r.Record(DirectionRead, []byte{4})
+
+	cr, err := NewCaptureReader(&buf)
+	assert.NoError(t, err)
+	assert.True(t, captureStart.Equal(cr.Started))
+
+	rec, err := cr.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Record{Time: 10 * time.Millisecond, Direction: DirectionWrite, Data: []byte{1, 2, 3}}, rec)
+	rec, err = cr.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Record{Time: 20 * time.Millisecond, Direction: DirectionRead,
+		Data: []byte("$GPGSV,1,1,00*79\r\n")}, rec)
+	_, err = cr.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRecorderWriteError(t *testing.T) {
+	_, err := NewRecorder(&failingWriter{})
+	assert.EqualError(t, err, "unable to write capture header: disk full")
+
+	r, err := NewRecorder(&failingWriter{remaining: captureHeaderSize})
+	assert.NoError(t, err)
+	r.Record(DirectionRead, []byte{1})
+	assert.EqualError(t, r.Close(), "unable to write capture record: disk full")
+}
+
+func TestCreateRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "session.cap")
+	r, err := CreateRecorder(path)
+	assert.NoError(t, err)
+	r.Record(DirectionRead, []byte{1})
+	assert.NoError(t, r.Close())
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, captureHeaderSize+recordHeaderSize+1, len(data))
+}
+
+func TestCaptureReaderInvalid(t *testing.T) {
+	_, err := NewCaptureReader(bytes.NewReader([]byte("STQ")))
+	assert.Error(t, err)
+
+	_, err = NewCaptureReader(bytes.NewReader([]byte("NOPE\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00")))
+	assert.EqualError(t, err, "not a capture file")
+
+	_, err = NewCaptureReader(bytes.NewReader([]byte("STQC\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00")))
+	assert.EqualError(t, err, "unsupported capture version 2")
+
+	// a record cut short ends the capture
+	buf := bytes.Buffer{}
+	r, err := NewRecorder(&buf)
+	assert.NoError(t, err)
+	r.Record(DirectionRead, []byte{1, 2, 3})
+	cr, err := NewCaptureReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.NoError(t, err)
+	_, err = cr.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestConnectRecordingAfterBaudDetection(t *testing.T) {
+	oldOpenPort := openPort
+	defer func() {
+		openPort = oldOpenPort
+	}()
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		m := MockSerialPort{}
+		if config.Baud == 115200 {
+			m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+		} else {
+			m.ReadBuf.Write([]byte{0x55, 0xaa, 0x55})
+		}
+		return &m, nil
+	}
+
+	buf := bytes.Buffer{}
+	r, err := NewRecorder(&buf)
+	assert.NoError(t, err)
+	c, err := ConnectWithOptions("fakeport", WithBaud(9600), WithBaudDetection(), WithRecorder(r))
+	assert.NoError(t, err)
+	assert.Equal(t, 115200, c.Baud())
+
+	cr, err := NewCaptureReader(&buf)
+	assert.NoError(t, err)
+	read, written := []byte{}, []byte{}
+	for {
+		rec, err := cr.Next()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		if rec.Direction == DirectionRead {
+			read = append(read, rec.Data...)
+		} else {
+			written = append(written, rec.Data...)
+		}
+	}
+	// only the query made when opening the port at the detected rate is recorded
+	assert.Equal(t, frameData(CommandQuerySoftwareVersion, []byte{1}, 0), written)
+	assert.Equal(t, frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0), read)
+}
+
+func TestConnectionRecording(t *testing.T) {
+	defer steppingClock(time.Millisecond)()
+
+	buf := bytes.Buffer{}
+	r, err := NewRecorder(&buf)
+	assert.NoError(t, err)
+
+	c, m := connection()
+	c.SetRecorder(r)
+	// everything read is recorded, including data that is skipped
+	input := []byte{0x01, 0xa0, 0x02}
+	input = append(input, "$GPGGA,,,,,,0,00,,,M,,M,,*67\r\n"...)
+	input = append(input, frameData(ResponseNavData, navData, 1)...)
+	input = append(input, nmeaSentence("GPGSV,1,1,00")...)
+	input = append(input, frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0)...)
+	m.ReadBuf.Write(input)
+	assert.NoError(t, c.WriteFrame(&Frame{ID: CommandQuerySoftwareVersion, Data: []byte{1}}))
+
+	c.SetRecorder(nil)
+	c.reader = nil
+	m.ReadBuf.Write(frameData(ResponseNavData, navData, 0))
+	_, err = c.ReadFrame()
+	assert.NoError(t, err)
+
+	cr, err := NewCaptureReader(&buf)
+	assert.NoError(t, err)
+	read, written := []byte{}, []byte{}
+	var last time.Duration
+	for {
+		rec, err := cr.Next()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		assert.True(t, rec.Time > last)
+		last = rec.Time
+		if rec.Direction == DirectionRead {
+			read = append(read, rec.Data...)
+		} else {
+			written = append(written, rec.Data...)
+		}
+	}
+	assert.Equal(t, input, read)
+	// the frame is sent again after the corrupt frame fails the wait for an ACK
+	assert.Equal(t, m.WriteBuf.Bytes(), written)
+	assert.Equal(t, 2*len(frameData(CommandQuerySoftwareVersion, []byte{1}, 0)), len(written))
+}
@@ -0,0 +1,142 @@
+package skytraq
+
+import (
+	"github.com/jd3nn1s/serial"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"time"
+)
+
+// allow mocking
+var sleep = time.Sleep
+
+// A serial port that returns the data read from a device in a capture file. Data written to the
+// port is discarded, as the responses to it are already in the capture.
+type replayPort struct {
+	path   string
+	file   *os.File
+	offset int64 // of the next record, so the capture can be reopened where it left off
+	speed  float64
+
+	capture *CaptureReader
+	pending []byte
+
+	// time the first record was replayed, and its time in the capture
+	started     time.Time
+	firstRecord time.Duration
+	paced       bool
+}
+
+// Open a capture file written by a Recorder as a connection. Data read from the device is returned
+// exactly as it was recorded, so Start and queries work as they did when the capture was made.
+// Writes are accepted and discarded.
+//
+// Reads are paced by the time each record was captured, divided by speed. A speed of 1 replays in
+// real time, and a speed of 0 replays as fast as possible. Reads return io.EOF at the end of the
+// capture.
+func OpenReplay(path string, speed float64) (*Connection, error) {
+	if speed < 0 {
+		return nil, errors.Errorf("invalid replay speed %v", speed)
+	}
+	rp := &replayPort{
+		path:  path,
+		speed: speed,
+	}
+	if err := rp.open(); err != nil {
+		return nil, err
+	}
+
+	c := newConnection(path)
+	c.port = rp
+	c.portOpener = func(*serial.Config) (SerialPort, error) {
+		// changing baud rate reopens the port, which continues the replay
+		return rp, rp.open()
+	}
+	return c, nil
+}
+
+func (rp *replayPort) open() error {
+	if rp.file != nil {
+		return nil
+	}
+	f, err := os.Open(rp.path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open capture file")
+	}
+	if rp.capture == nil {
+		rp.capture, err = NewCaptureReader(f)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		rp.offset = captureHeaderSize
+	} else {
+		if _, err := f.Seek(rp.offset, io.SeekStart); err != nil {
+			f.Close()
+			return errors.Wrapf(err, "unable to resume capture file")
+		}
+		rp.capture.r = f
+	}
+	rp.file = f
+	return nil
+}
+
+// Read returns at most one record at a time, as the port did when it was recorded, so that the
+// connection's buffer holds the same data it did.
+func (rp *replayPort) Read(p []byte) (int, error) {
+	if rp.file == nil {
+		return 0, errors.New("replay port is closed")
+	}
+	for len(rp.pending) == 0 {
+		rec, err := rp.capture.Next()
+		if err != nil {
+			return 0, err
+		}
+		rp.offset += int64(recordHeaderSize + len(rec.Data))
+		if rec.Direction != DirectionRead {
+			continue
+		}
+		rp.pace(rec.Time)
+		rp.pending = rec.Data
+	}
+	n := copy(p, rp.pending)
+	rp.pending = rp.pending[n:]
+	return n, nil
+}
+
+// Wait until the record is due.
+func (rp *replayPort) pace(t time.Duration) {
+	if rp.speed == 0 {
+		return
+	}
+	if !rp.paced {
+		rp.started = timeNow()
+		rp.firstRecord = t
+		rp.paced = true
+		return
+	}
+	due := rp.started.Add(time.Duration(float64(t-rp.firstRecord) / rp.speed))
+	if wait := due.Sub(timeNow()); wait > 0 {
+		sleep(wait)
+	}
+}
+
+func (rp *replayPort) Write(p []byte) (int, error) {
+	logrus.Debugf("discarding %v bytes written to replay", len(p))
+	return len(p), nil
+}
+
+func (rp *replayPort) Flush() error {
+	return nil
+}
+
+func (rp *replayPort) Close() error {
+	if rp.file == nil {
+		return nil
+	}
+	err := rp.file.Close()
+	rp.file = nil
+	return err
+}
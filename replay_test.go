@@ -0,0 +1,158 @@
+package skytraq
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Write a capture of a session to a temporary file, returning its path and a cleanup function.
+func captureFile(t *testing.T, records ...Record) (string, func()) {
+	dir, err := ioutil.TempDir("", "replay")
+	assert.NoError(t, err)
+	path := filepath.Join(dir, "session.cap")
+
+	restoreClock := steppingClock(0)
+	r, err := CreateRecorder(path)
+	assert.NoError(t, err)
+	for _, rec := range records {
+		setTime(captureStart.Add(rec.Time))
+		r.Record(rec.Direction, rec.Data)
+	}
+	assert.NoError(t, r.Close())
+	restoreClock()
+
+	return path, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestOpenReplay(t *testing.T) {
+	gga := nmeaSentence("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,")
+	path, cleanup := captureFile(t,
+		Record{Time: 0, Direction: DirectionWrite, Data: frameData(CommandQuerySoftwareVersion, []byte{1}, 0)},
+		Record{Time: 0, Direction: DirectionRead,
+			Data: frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0)},
+		Record{Time: 0, Direction: DirectionRead, Data: frameData(ResponseSoftwareVersion, versionData, 0)},
+		Record{Time: time.Second, Direction: DirectionRead, Data: []byte(gga)},
+		Record{Time: time.Second, Direction: DirectionRead, Data: frameData(ResponseNavData, navData, 0)},
+	)
+	defer cleanup()
+
+	c, err := OpenReplay(path, 0)
+	assert.NoError(t, err)
+	defer c.Close()
+
+	version, err := c.QuerySoftwareVersion(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2007, version.Revision.Major)
+
+	counts := map[string]int{}
+	err = c.Start(context.Background(), Callbacks{
+		GGA:     func(GGA) { counts["GGA"]++ },
+		NavData: func(NavData) { counts["nav"]++ },
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, map[string]int{"GGA": 1, "nav": 1}, counts)
+}
+
+func TestOpenReplayPaced(t *testing.T) {
+	path, cleanup := captureFile(t,
+		Record{Time: 5 * time.Second, Direction: DirectionRead, Data: frameData(ResponseNavData, navData, 0)},
+		Record{Time: 5 * time.Second, Direction: DirectionWrite, Data: []byte{1}},
+		Record{Time: 6 * time.Second, Direction: DirectionRead, Data: frameData(ResponseNavData, navData, 0)},
+		Record{Time: 9 * time.Second, Direction: DirectionRead, Data: frameData(ResponseNavData, navData, 0)},
+	)
+	defer cleanup()
+
+	oldTimeNow, oldSleep := timeNow, sleep
+	defer func() {
+		timeNow, sleep = oldTimeNow, oldSleep
+	}()
+	now := captureStart
+	timeNow = func() time.Time {
+		return now
+	}
+	var sleeps []time.Duration
+	sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+	}
+
+	c, err := OpenReplay(path, 2)
+	assert.NoError(t, err)
+	defer c.Close()
+	err = c.Start(context.Background(), Callbacks{})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 1500 * time.Millisecond}, sleeps)
+}
+
+func TestOpenReplayReopen(t *testing.T) {
+	path, cleanup := captureFile(t,
+		Record{Direction: DirectionRead, Data: frameData(ResponseACK, []byte{byte(CommandConfigureSerialPort)}, 0)},
+		Record{Direction: DirectionRead,
+			Data: frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0)},
+		Record{Direction: DirectionRead, Data: frameData(ResponseNavData, navData, 0)},
+	)
+	defer cleanup()
+
+	c, err := OpenReplay(path, 0)
+	assert.NoError(t, err)
+	defer c.Close()
+
+	assert.NoError(t, c.ConfigureSerialPort(context.Background(), 115200, UpdateSRAM))
+	f, err := c.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, ResponseNavData, f.ID)
+}
+
+func TestOpenReplayInvalid(t *testing.T) {
+	_, err := OpenReplay("missing.cap", 0)
+	assert.Error(t, err)
+
+	path, cleanup := captureFile(t)
+	defer cleanup()
+	_, err = OpenReplay(path, -1)
+	assert.EqualError(t, err, "invalid replay speed -1")
+}
+
+func TestReplayRecordedSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.cap")
+
+	r, err := CreateRecorder(path)
+	assert.NoError(t, err)
+	c, m := connection()
+	c.SetRecorder(r)
+	m.readLimit = 7
+	m.ReadBuf.WriteString("$GPGGA,,,,,,0,00,,,M,,M,,*67\r\n")
+	m.ReadBuf.Write([]byte{0xa0, 0x00})
+	m.ReadBuf.WriteString(nmeaSentence("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"))
+	m.ReadBuf.Write(frameData(ResponseNavData, navData, 0))
+
+	record := func(c *Connection) []string {
+		var events []string
+		err := c.Start(context.Background(), Callbacks{
+			GGA:     func(GGA) { events = append(events, "GGA") },
+			NavData: func(NavData) { events = append(events, "nav") },
+		})
+		events = append(events, errors.Cause(err).Error())
+		return events
+	}
+	live := record(c)
+	assert.Equal(t, []string{"GGA", "nav", "EOF"}, live)
+	assert.NoError(t, r.Close())
+
+	replay, err := OpenReplay(path, 0)
+	assert.NoError(t, err)
+	defer replay.Close()
+	assert.Equal(t, live, record(replay))
+}
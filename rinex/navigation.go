@@ -0,0 +1,262 @@
+package rinex
+
+import (
+	"fmt"
+	"github.com/jd3nn1s/skytraq"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// user range accuracy in meters for each URA index
+var uraMeters = []float64{2, 2.8, 4, 5.7, 8, 11.3, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 6144}
+
+// Ephemeris and clock parameters decoded from subframes 1 to 3 of the GPS navigation message.
+// Angles are in radians.
+type gpsEphemeris struct {
+	PRN  int
+	Week int // truncated to 10 bits until resolved against a reference week
+	TOW  int // time of week of the HOW of subframe 1, in seconds
+
+	// subframe 1
+	CodesOnL2 int
+	URA       int
+	Health    int
+	IODC      int
+	L2PFlag   int
+	TGD       float64
+	TOC       float64
+	AF0       float64
+	AF1       float64
+	AF2       float64
+
+	// subframe 2
+	IODE    int
+	Crs     float64
+	DeltaN  float64
+	M0      float64
+	Cuc     float64
+	E       float64
+	Cus     float64
+	SqrtA   float64
+	TOE     float64
+	FitFlag int
+
+	// subframe 3
+	iode3    int
+	Cic      float64
+	Omega0   float64
+	Cis      float64
+	I0       float64
+	Crc      float64
+	Omega    float64
+	OmegaDot float64
+	IDOT     float64
+}
+
+// Read length bits starting at a 1-based bit within a 1-based word of a subframe, as numbered in
+// IS-GPS-200 with parity removed. Fields may continue into the following word.
+func bits(words []byte, word, bit, length int) uint32 {
+	start := (word-1)*24 + bit - 1
+	var v uint32
+	for i := start; i < start+length; i++ {
+		v = v<<1 | uint32(words[i/8]>>(7-uint(i%8))&1)
+	}
+	return v
+}
+
+// As bits, interpreting the field as two's complement.
+func signedBits(words []byte, word, bit, length int) int32 {
+	v := bits(words, word, bit, length)
+	return int32(v<<uint(32-length)) >> uint(32-length)
+}
+
+func scaled(v float64, exponent int) float64 {
+	return math.Ldexp(v, exponent)
+}
+
+func decodeSubframe1(e *gpsEphemeris, w []byte) {
+	e.TOW = int(bits(w, 2, 1, 17)) * 6
+	e.Week = int(bits(w, 3, 1, 10))
+	e.CodesOnL2 = int(bits(w, 3, 11, 2))
+	e.URA = int(bits(w, 3, 13, 4))
+	e.Health = int(bits(w, 3, 17, 6))
+	e.IODC = int(bits(w, 3, 23, 2)<<8 | bits(w, 8, 1, 8))
+	e.L2PFlag = int(bits(w, 4, 1, 1))
+	e.TGD = scaled(float64(signedBits(w, 7, 17, 8)), -31)
+	e.TOC = scaled(float64(bits(w, 8, 9, 16)), 4)
+	e.AF2 = scaled(float64(signedBits(w, 9, 1, 8)), -55)
+	e.AF1 = scaled(float64(signedBits(w, 9, 9, 16)), -43)
+	e.AF0 = scaled(float64(signedBits(w, 10, 1, 22)), -31)
+}
+
+func decodeSubframe2(e *gpsEphemeris, w []byte) {
+	e.IODE = int(bits(w, 3, 1, 8))
+	e.Crs = scaled(float64(signedBits(w, 3, 9, 16)), -5)
+	e.DeltaN = scaled(float64(signedBits(w, 4, 1, 16)), -43) * math.Pi
+	e.M0 = scaled(float64(signedBits(w, 4, 17, 32)), -31) * math.Pi
+	e.Cuc = scaled(float64(signedBits(w, 6, 1, 16)), -29)
+	e.E = scaled(float64(bits(w, 6, 17, 32)), -33)
+	e.Cus = scaled(float64(signedBits(w, 8, 1, 16)), -29)
+	e.SqrtA = scaled(float64(bits(w, 8, 17, 32)), -19)
+	e.TOE = scaled(float64(bits(w, 10, 1, 16)), 4)
+	e.FitFlag = int(bits(w, 10, 17, 1))
+}
+
+func decodeSubframe3(e *gpsEphemeris, w []byte) {
+	e.Cic = scaled(float64(signedBits(w, 3, 1, 16)), -29)
+	e.Omega0 = scaled(float64(signedBits(w, 3, 17, 32)), -31) * math.Pi
+	e.Cis = scaled(float64(signedBits(w, 5, 1, 16)), -29)
+	e.I0 = scaled(float64(signedBits(w, 5, 17, 32)), -31) * math.Pi
+	e.Crc = scaled(float64(signedBits(w, 7, 1, 16)), -5)
+	e.Omega = scaled(float64(signedBits(w, 7, 17, 32)), -31) * math.Pi
+	e.OmegaDot = scaled(float64(signedBits(w, 9, 1, 24)), -43) * math.Pi
+	e.iode3 = int(bits(w, 10, 1, 8))
+	e.IDOT = scaled(float64(signedBits(w, 10, 9, 14)), -43) * math.Pi
+}
+
+// Writes a RINEX GPS navigation file from navigation message subframes. A record is written for a
+// satellite once subframes 1 to 3 with the same issue of data have been received, and again only
+// when the issue of data changes.
+//
+// The navigation message only carries the week number modulo 1024, which is resolved against the
+// week of the latest measurement time. Records completed before any measurement time is received
+// are held until one is, or until Flush, which resolves them against the header date instead.
+type NavigationWriter struct {
+	lw            lineWriter
+	header        Header
+	headerWritten bool
+
+	subframes     map[int]*[3][]byte // subframes 1 to 3 for each PRN
+	written       map[int]int        // IODE last written for each PRN
+	referenceWeek int                // week of the latest measurement time, 0 until one is received
+	pending       []*gpsEphemeris    // records waiting for a reference week
+}
+
+func NewNavigationWriter(w io.Writer, header Header) *NavigationWriter {
+	return &NavigationWriter{
+		lw:        lineWriter{w: w},
+		header:    header,
+		subframes: map[int]*[3][]byte{},
+		written:   map[int]int{},
+	}
+}
+
+// First error that occurred when writing.
+func (nw *NavigationWriter) Err() error {
+	return nw.lw.err
+}
+
+// Write any records held waiting for a measurement time, and the header if it has not yet been
+// written so that a file without any records is valid.
+func (nw *NavigationWriter) Flush() error {
+	if len(nw.pending) > 0 {
+		nw.referenceWeek = int(nw.header.date().Sub(skytraq.GPSTime{}.Time()) / (7 * 24 * time.Hour))
+		nw.writePending()
+	}
+	nw.writeHeader()
+	return nw.lw.err
+}
+
+// Use the week of the measurement to resolve the week number of navigation records.
+func (nw *NavigationWriter) MeasurementTime(mt skytraq.MeasurementTime) {
+	nw.referenceWeek = mt.Week
+	nw.writePending()
+}
+
+func (nw *NavigationWriter) writePending() {
+	for _, e := range nw.pending {
+		e.Week = skytraq.ResolveWeekNear(e.Week, nw.referenceWeek)
+		nw.writeRecord(e)
+	}
+	nw.pending = nil
+}
+
+func (nw *NavigationWriter) writeHeader() {
+	if nw.headerWritten {
+		return
+	}
+	nw.headerWritten = true
+	nw.lw.header("RINEX VERSION / TYPE", "%9.2f%11s%-20s%-20s", version, "", "N: GNSS NAV DATA", "G: GPS")
+	nw.lw.programHeader(nw.header)
+	nw.lw.header("END OF HEADER", "")
+}
+
+// Store a subframe, writing a navigation record when it completes a set of ephemeris.
+func (nw *NavigationWriter) Subframe(sf skytraq.Subframe) {
+	if sf.SVID < 1 || sf.SVID > maxGPSPRN || sf.SubframeID < 1 || sf.SubframeID > 3 {
+		return
+	}
+	set, ok := nw.subframes[sf.SVID]
+	if !ok {
+		set = &[3][]byte{}
+		nw.subframes[sf.SVID] = set
+	}
+	set[sf.SubframeID-1] = append([]byte(nil), sf.Words[:]...)
+
+	if set[0] == nil || set[1] == nil || set[2] == nil {
+		return
+	}
+	e := gpsEphemeris{PRN: sf.SVID}
+	decodeSubframe1(&e, set[0])
+	decodeSubframe2(&e, set[1])
+	decodeSubframe3(&e, set[2])
+	if e.IODE != e.iode3 || e.IODE != e.IODC&0xff {
+		// subframes are from different uploads, wait for the rest of the new set
+		return
+	}
+	if iode, ok := nw.written[sf.SVID]; ok && iode == e.IODE {
+		return
+	}
+	nw.written[sf.SVID] = e.IODE
+	if nw.referenceWeek == 0 {
+		nw.pending = append(nw.pending, &e)
+		return
+	}
+	e.Week = skytraq.ResolveWeekNear(e.Week, nw.referenceWeek)
+	nw.writeRecord(&e)
+}
+
+// Format a value as D19.12 using an E exponent.
+func d19(v float64) string {
+	return fmt.Sprintf("%19.12E", v)
+}
+
+func (nw *NavigationWriter) orbit(values ...float64) {
+	line := "    "
+	for _, v := range values {
+		line += d19(v)
+	}
+	nw.lw.line("%s", strings.TrimRight(line, " "))
+}
+
+func (nw *NavigationWriter) writeRecord(e *gpsEphemeris) {
+	nw.writeHeader()
+
+	// clock reference time in the week of the ephemeris, handling a TOC just before the week
+	// boundary when subframe 1 was sent just after it
+	week := e.Week
+	if e.TOC-float64(e.TOW) > 302400 {
+		week--
+	} else if e.TOC-float64(e.TOW) < -302400 {
+		week++
+	}
+	toc := skytraq.GPSTime{Week: week, TimeOfWeek: time.Duration(e.TOC) * time.Second}.Time()
+
+	fitInterval := 4.0
+	if e.FitFlag != 0 {
+		fitInterval = 6.0
+	}
+
+	nw.lw.line("%s %04d %02d %02d %02d %02d %02d%s%s%s", gpsSatellite(e.PRN),
+		toc.Year(), toc.Month(), toc.Day(), toc.Hour(), toc.Minute(), toc.Second(),
+		d19(e.AF0), d19(e.AF1), d19(e.AF2))
+	nw.orbit(float64(e.IODE), e.Crs, e.DeltaN, e.M0)
+	nw.orbit(e.Cuc, e.E, e.Cus, e.SqrtA)
+	nw.orbit(e.TOE, e.Cic, e.Omega0, e.Cis)
+	nw.orbit(e.I0, e.Crc, e.Omega, e.OmegaDot)
+	nw.orbit(e.IDOT, float64(e.CodesOnL2), float64(e.Week), float64(e.L2PFlag))
+	nw.orbit(uraMeters[e.URA], float64(e.Health), e.TGD, float64(e.IODC))
+	nw.orbit(float64(e.TOW-6), fitInterval)
+}
@@ -0,0 +1,178 @@
+package rinex
+
+import (
+	"bytes"
+	"github.com/jd3nn1s/skytraq"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"strings"
+	"testing"
+)
+
+func setBits(words []byte, word, bit, length int, v int64) {
+	start := (word-1)*24 + bit - 1
+	for i := 0; i < length; i++ {
+		pos := start + i
+		mask := byte(1) << uint(7-pos%8)
+		if v>>uint(length-1-i)&1 != 0 {
+			words[pos/8] |= mask
+		} else {
+			words[pos/8] &^= mask
+		}
+	}
+}
+
+// subframes 1 to 3 for PRN 5 broadcast in week 2200 (truncated to 152) with IODE 77
+func testSubframes() []skytraq.Subframe {
+	sf := make([]skytraq.Subframe, 3)
+	for i := range sf {
+		sf[i].SVID = 5
+		sf[i].SubframeID = i + 1
+		// HOW time of week count and subframe ID
+		setBits(sf[i].Words[:], 2, 1, 17, int64(7200+i))
+		setBits(sf[i].Words[:], 2, 20, 3, int64(i+1))
+	}
+
+	w := sf[0].Words[:]
+	setBits(w, 3, 1, 10, 2200-2048)
+	setBits(w, 3, 11, 2, 1)    // codes on L2
+	setBits(w, 3, 13, 4, 2)    // URA index
+	setBits(w, 3, 17, 6, 0)    // health
+	setBits(w, 3, 23, 2, 1)    // IODC MSBs
+	setBits(w, 8, 1, 8, 77)    // IODC LSBs
+	setBits(w, 7, 17, 8, -12)  // TGD
+	setBits(w, 8, 9, 16, 2700) // TOC / 16
+	setBits(w, 9, 1, 8, 0)
+	setBits(w, 9, 9, 16, -100)
+	setBits(w, 10, 1, 22, 123456)
+
+	w = sf[1].Words[:]
+	setBits(w, 3, 1, 8, 77)
+	setBits(w, 3, 9, 16, -336)
+	setBits(w, 4, 1, 16, 12000)
+	setBits(w, 4, 17, 8, 0x40)
+	setBits(w, 5, 1, 24, 0)
+	setBits(w, 6, 1, 16, -1000)
+	setBits(w, 6, 17, 8, 0)
+	setBits(w, 7, 1, 24, 0x200000)
+	setBits(w, 8, 1, 16, 2000)
+	setBits(w, 8, 17, 8, 0xa1)
+	setBits(w, 9, 1, 24, 0x0d2e4f)
+	setBits(w, 10, 1, 16, 2700)
+	setBits(w, 10, 17, 1, 0)
+
+	w = sf[2].Words[:]
+	setBits(w, 3, 1, 16, 50)
+	setBits(w, 3, 17, 8, 0xc0)
+	setBits(w, 4, 1, 24, 0)
+	setBits(w, 5, 1, 16, -50)
+	setBits(w, 5, 17, 8, 0x28)
+	setBits(w, 6, 1, 24, 0)
+	setBits(w, 7, 1, 16, 6400)
+	setBits(w, 7, 17, 8, 0x20)
+	setBits(w, 8, 1, 24, 0)
+	setBits(w, 9, 1, 24, -20000)
+	setBits(w, 10, 1, 8, 77)
+	setBits(w, 10, 9, 14, -300)
+	return sf
+}
+
+func TestBits(t *testing.T) {
+	words := make([]byte, 30)
+	setBits(words, 4, 17, 32, -2)
+	assert.Equal(t, uint32(0xfffffffe), bits(words, 4, 17, 32))
+	assert.Equal(t, int32(-2), signedBits(words, 4, 17, 32))
+	assert.Equal(t, uint32(0xff), bits(words, 4, 17, 8))
+	assert.Equal(t, uint32(0), bits(words, 4, 1, 16))
+
+	setBits(words, 1, 1, 3, 5)
+	assert.Equal(t, byte(0xa0), words[0])
+}
+
+func TestDecodeSubframes(t *testing.T) {
+	sf := testSubframes()
+	e := gpsEphemeris{}
+	decodeSubframe1(&e, sf[0].Words[:])
+	decodeSubframe2(&e, sf[1].Words[:])
+	decodeSubframe3(&e, sf[2].Words[:])
+
+	assert.Equal(t, 43200, e.TOW)
+	assert.Equal(t, 2200-2048, e.Week)
+	assert.Equal(t, 1, e.CodesOnL2)
+	assert.Equal(t, 2, e.URA)
+	assert.Equal(t, 256+77, e.IODC)
+	assert.Equal(t, -12*math.Pow(2, -31), e.TGD)
+	assert.Equal(t, 43200.0, e.TOC)
+	assert.Equal(t, -100*math.Pow(2, -43), e.AF1)
+	assert.Equal(t, 123456*math.Pow(2, -31), e.AF0)
+
+	assert.Equal(t, 77, e.IODE)
+	assert.Equal(t, -10.5, e.Crs)
+	assert.Equal(t, 12000*math.Pow(2, -43)*math.Pi, e.DeltaN)
+	assert.Equal(t, 0.5*math.Pi, e.M0)
+	assert.Equal(t, 0x200000*math.Pow(2, -33), e.E)
+	assert.Equal(t, float64(0xa10d2e4f)*math.Pow(2, -19), e.SqrtA)
+	assert.Equal(t, 43200.0, e.TOE)
+
+	assert.Equal(t, 77, e.iode3)
+	assert.Equal(t, -0.5*math.Pi, e.Omega0)
+	assert.Equal(t, 0.3125*math.Pi, e.I0)
+	assert.Equal(t, 200.0, e.Crc)
+	assert.Equal(t, 0.25*math.Pi, e.Omega)
+	assert.Equal(t, -20000*math.Pow(2, -43)*math.Pi, e.OmegaDot)
+	assert.Equal(t, -300*math.Pow(2, -43)*math.Pi, e.IDOT)
+}
+
+func TestNavigationWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	nw := NewNavigationWriter(&buf, testHeader)
+	for _, sf := range testSubframes() {
+		nw.Subframe(sf)
+	}
+	// repeated subframes with the same IODE do not write another record
+	for _, sf := range testSubframes() {
+		nw.Subframe(sf)
+	}
+	assert.NoError(t, nw.Flush())
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	assert.Equal(t, 3+8, len(lines))
+	assert.Equal(t, "     3.04           N: GNSS NAV DATA    G: GPS              RINEX VERSION / TYPE", lines[0])
+	assert.Equal(t, "                                                            END OF HEADER", lines[2])
+	assert.Equal(t, "G05 2022 03 06 12 00 00 5.748867988586E-05-1.136868377216E-11 0.000000000000E+00", lines[3])
+	assert.Equal(t, "     7.700000000000E+01-1.050000000000E+01 4.285892810353E-09 1.570796326795E+00", lines[4])
+	assert.Equal(t, "     4.320000000000E+04 9.313225746155E-08-1.570796326795E+00-9.313225746155E-08", lines[6])
+	assert.Equal(t, "    -1.071473202588E-10 1.000000000000E+00 2.200000000000E+03 0.000000000000E+00", lines[8])
+	assert.Equal(t, "     4.000000000000E+00 0.000000000000E+00-5.587935447693E-09 3.330000000000E+02", lines[9])
+	assert.Equal(t, "     4.319400000000E+04 4.000000000000E+00", lines[10])
+}
+
+func TestNavigationWriterMismatchedIODE(t *testing.T) {
+	buf := bytes.Buffer{}
+	nw := NewNavigationWriter(&buf, testHeader)
+	sf := testSubframes()
+	setBits(sf[2].Words[:], 10, 1, 8, 78)
+	for _, s := range sf {
+		nw.Subframe(s)
+	}
+	assert.NoError(t, nw.Flush())
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"))
+}
+
+func TestNavigationWriterReferenceWeek(t *testing.T) {
+	buf := bytes.Buffer{}
+	nw := NewNavigationWriter(&buf, testHeader)
+	for _, sf := range testSubframes() {
+		nw.Subframe(sf)
+	}
+	// held until the week can be resolved
+	assert.Equal(t, 0, buf.Len())
+
+	// a capture from before the second rollover resolves to the earlier week
+	nw.MeasurementTime(skytraq.MeasurementTime{Week: 1180})
+	assert.NoError(t, nw.Flush())
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	assert.Equal(t, 3+8, len(lines))
+	assert.Equal(t, "G05 2002 07 21 12 00 00", lines[3][:23])
+	assert.Equal(t, " 1.176000000000E+03", lines[8][42:61])
+}
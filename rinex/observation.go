@@ -0,0 +1,164 @@
+package rinex
+
+import (
+	"fmt"
+	"github.com/jd3nn1s/skytraq"
+	"github.com/sirupsen/logrus"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	maxGPSPRN = 32
+
+	// loss of lock indicator flagging a possible cycle slip
+	lliCycleSlip = 1
+)
+
+// observation types written for each satellite, in order
+var observationTypes = []string{"C1C", "L1C", "D1C", "S1C"}
+
+// Writes a RINEX observation file for GPS L1 C/A. Epochs are formed from a measurement time message
+// and the raw measurements that follow it with the same issue of data. The header is written with
+// the first epoch, as it includes the time of the first observation, or by Flush. Measurements of
+// satellites other than GPS are not written.
+type ObservationWriter struct {
+	lw            lineWriter
+	header        Header
+	headerWritten bool
+
+	pending     bool
+	pendingIOD  int
+	pendingTime time.Time
+}
+
+func NewObservationWriter(w io.Writer, header Header) *ObservationWriter {
+	return &ObservationWriter{
+		lw:     lineWriter{w: w},
+		header: header,
+	}
+}
+
+// First error that occurred when writing.
+func (ow *ObservationWriter) Err() error {
+	return ow.lw.err
+}
+
+// Write the header if it has not yet been written, so that a file without any epochs is valid. The
+// time of the first observation is then the header date.
+func (ow *ObservationWriter) Flush() error {
+	if !ow.headerWritten {
+		ow.writeHeader(ow.header.date().UTC())
+		ow.headerWritten = true
+	}
+	return ow.lw.err
+}
+
+// Start an epoch at the measurement time.
+func (ow *ObservationWriter) MeasurementTime(mt skytraq.MeasurementTime) {
+	if ow.pending {
+		logrus.WithField("iod", ow.pendingIOD).Warn("no raw measurements received for epoch")
+	}
+	ow.pending = true
+	ow.pendingIOD = mt.IOD
+	ow.pendingTime = mt.GPSTime().Time()
+}
+
+// Write the measurements as an epoch if they belong to the pending measurement time.
+func (ow *ObservationWriter) RawMeasurements(rm skytraq.RawMeasurements) {
+	if !ow.pending || rm.IOD != ow.pendingIOD {
+		logrus.WithField("iod", rm.IOD).Warn("ignoring raw measurements without a measurement time")
+		return
+	}
+	ow.pending = false
+
+	if !ow.headerWritten {
+		ow.writeHeader(ow.pendingTime)
+		ow.headerWritten = true
+	}
+	ow.writeEpoch(ow.pendingTime, rm.Measurements)
+}
+
+func (ow *ObservationWriter) writeHeader(firstObs time.Time) {
+	h := ow.header
+	lw := &ow.lw
+	lw.header("RINEX VERSION / TYPE", "%9.2f%11s%-20s%-20s", version, "", "OBSERVATION DATA", "G: GPS")
+	lw.programHeader(h)
+	lw.header("MARKER NAME", "%s", truncate(h.MarkerName, 60))
+	lw.header("OBSERVER / AGENCY", "%-20s%-40s", truncate(h.Observer, 20), truncate(h.Agency, 40))
+	lw.header("REC # / TYPE / VERS", "%-20s%-20s%-20s", truncate(h.ReceiverNumber, 20),
+		truncate(h.ReceiverType, 20), truncate(h.ReceiverVersion, 20))
+	lw.header("ANT # / TYPE", "%-20s%-20s", truncate(h.AntennaNumber, 20), truncate(h.AntennaType, 20))
+	lw.header("APPROX POSITION XYZ", "%14.4f%14.4f%14.4f",
+		h.ApproxPosition.X, h.ApproxPosition.Y, h.ApproxPosition.Z)
+	lw.header("ANTENNA: DELTA H/E/N", "%14.4f%14.4f%14.4f",
+		h.AntennaDelta.Up, h.AntennaDelta.East, h.AntennaDelta.North)
+
+	types := ""
+	for _, t := range observationTypes {
+		types += " " + t
+	}
+	lw.header("SYS / # / OBS TYPES", "G  %3d%s", len(observationTypes), types)
+
+	lw.header("TIME OF FIRST OBS", "%6d%6d%6d%6d%6d%13.7f%5s%3s",
+		firstObs.Year(), firstObs.Month(), firstObs.Day(), firstObs.Hour(), firstObs.Minute(),
+		float64(firstObs.Second())+float64(firstObs.Nanosecond())/1e9, "", "GPS")
+	// L1C is the reference signal so its phase needs no correction
+	lw.header("SYS / PHASE SHIFT", "G %s %8.5f", observationTypes[1], 0.0)
+	lw.header("END OF HEADER", "")
+}
+
+func (ow *ObservationWriter) writeEpoch(epoch time.Time, measurements []skytraq.RawMeasurement) {
+	records := []string{}
+	for _, m := range measurements {
+		if m.SVID < 1 || m.SVID > maxGPSPRN {
+			continue
+		}
+		records = append(records, observationRecord(m))
+	}
+
+	ow.lw.line("> %4d %02d %02d %02d %02d%11.7f  %d%3d",
+		epoch.Year(), epoch.Month(), epoch.Day(), epoch.Hour(), epoch.Minute(),
+		float64(epoch.Second())+float64(epoch.Nanosecond())/1e9, 0, len(records))
+	for _, r := range records {
+		ow.lw.line("%s", r)
+	}
+}
+
+// Signal strength indicator from 1 to 9 for a C/N0 in dB-Hz.
+func signalStrength(cn0 int) int {
+	ssi := cn0 / 6
+	if ssi < 1 {
+		return 1
+	} else if ssi > 9 {
+		return 9
+	}
+	return ssi
+}
+
+func observationRecord(m skytraq.RawMeasurement) string {
+	ssi := signalStrength(m.CN0)
+	value := func(v float64, available bool, lli int) string {
+		if !available || math.Abs(v) >= 1e10 {
+			return strings.Repeat(" ", 16)
+		}
+		flag := " "
+		if lli != 0 {
+			flag = fmt.Sprint(lli)
+		}
+		return fmt.Sprintf("%14.3f%s%d", v, flag, ssi)
+	}
+
+	lli := 0
+	if m.Indicator&skytraq.CycleSlipPossible != 0 {
+		lli = lliCycleSlip
+	}
+	record := gpsSatellite(m.SVID) +
+		value(m.Pseudorange, m.Indicator&skytraq.PseudorangeAvailable != 0, 0) +
+		value(m.CarrierPhase, m.Indicator&skytraq.CarrierPhaseAvailable != 0, lli) +
+		value(m.Doppler, m.Indicator&skytraq.DopplerAvailable != 0, 0) +
+		value(float64(m.CN0), true, 0)
+	return strings.TrimRight(record, " ")
+}
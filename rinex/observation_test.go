@@ -0,0 +1,129 @@
+package rinex
+
+import (
+	"bytes"
+	"github.com/jd3nn1s/skytraq"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testHeader = Header{
+	RunBy:           "tester",
+	Date:            time.Date(2022, time.March, 8, 1, 2, 3, 0, time.UTC),
+	MarkerName:      "ROOF",
+	Observer:        "observer",
+	Agency:          "agency",
+	ReceiverNumber:  "1234",
+	ReceiverType:    "SKYTRAQ VENUS8",
+	ReceiverVersion: "1.0",
+	AntennaType:     "PATCH",
+	ApproxPosition:  skytraq.ECEF{X: -4646051.2721, Y: 2553206.3422, Z: -3534372.3879},
+	AntennaDelta:    skytraq.ENU{Up: 1.5},
+}
+
+func TestObservationWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	ow := NewObservationWriter(&buf, testHeader)
+
+	ow.MeasurementTime(skytraq.MeasurementTime{IOD: 1, Week: 2200, TimeOfWeek: 36*time.Hour + 500*time.Millisecond})
+	ow.RawMeasurements(skytraq.RawMeasurements{
+		IOD: 1,
+		Measurements: []skytraq.RawMeasurement{
+			{SVID: 3, CN0: 45, Pseudorange: 21234567.891, CarrierPhase: 111589201.125, Doppler: -1500.25,
+				Indicator: skytraq.PseudorangeAvailable | skytraq.DopplerAvailable | skytraq.CarrierPhaseAvailable |
+					skytraq.CycleSlipPossible},
+			// SBAS is not written
+			{SVID: 133, CN0: 40, Pseudorange: 38000000, Indicator: skytraq.PseudorangeAvailable},
+			{SVID: 17, CN0: 5, Pseudorange: 23456789.125, Doppler: 250.5,
+				Indicator: skytraq.PseudorangeAvailable | skytraq.DopplerAvailable},
+		},
+	})
+	// raw measurements for a different epoch are ignored
+	ow.MeasurementTime(skytraq.MeasurementTime{IOD: 2, Week: 2200, TimeOfWeek: 36*time.Hour + time.Second})
+	ow.RawMeasurements(skytraq.RawMeasurements{IOD: 3})
+	ow.MeasurementTime(skytraq.MeasurementTime{IOD: 4, Week: 2200, TimeOfWeek: 36*time.Hour + 1500*time.Millisecond})
+	ow.RawMeasurements(skytraq.RawMeasurements{IOD: 4})
+	assert.NoError(t, ow.Err())
+
+	expected := strings.Join([]string{
+		"     3.04           OBSERVATION DATA    G: GPS              RINEX VERSION / TYPE",
+		"skytraq             tester              20220308 010203 UTC PGM / RUN BY / DATE",
+		"ROOF                                                        MARKER NAME",
+		"observer            agency                                  OBSERVER / AGENCY",
+		"1234                SKYTRAQ VENUS8      1.0                 REC # / TYPE / VERS",
+		"                    PATCH                                   ANT # / TYPE",
+		" -4646051.2721  2553206.3422 -3534372.3879                  APPROX POSITION XYZ",
+		"        1.5000        0.0000        0.0000                  ANTENNA: DELTA H/E/N",
+		"G    4 C1C L1C D1C S1C                                      SYS / # / OBS TYPES",
+		"  2022     3     7    12     0    0.5000000     GPS         TIME OF FIRST OBS",
+		"G L1C  0.00000                                              SYS / PHASE SHIFT",
+		"                                                            END OF HEADER",
+		"> 2022 03 07 12 00  0.5000000  0  2",
+		"G03  21234567.891 7 111589201.12517     -1500.250 7        45.000 7",
+		"G17  23456789.125 1                       250.500 1         5.000 1",
+		"> 2022 03 07 12 00  1.5000000  0  0",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestObservationWriterFlush(t *testing.T) {
+	buf := bytes.Buffer{}
+	ow := NewObservationWriter(&buf, testHeader)
+	assert.NoError(t, ow.Flush())
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	assert.Equal(t, 12, len(lines))
+	assert.Equal(t, "  2022     3     8     1     2    3.0000000     GPS         TIME OF FIRST OBS", lines[9])
+	assert.Equal(t, "                                                            END OF HEADER", lines[11])
+
+	// the header is only written once
+	ow.MeasurementTime(skytraq.MeasurementTime{IOD: 1, Week: 2200})
+	ow.RawMeasurements(skytraq.RawMeasurements{IOD: 1})
+	assert.NoError(t, ow.Flush())
+	assert.Equal(t, 1, strings.Count(buf.String(), "END OF HEADER"))
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestObservationWriterError(t *testing.T) {
+	ow := NewObservationWriter(failingWriter{}, testHeader)
+	ow.MeasurementTime(skytraq.MeasurementTime{IOD: 1, Week: 2200})
+	ow.RawMeasurements(skytraq.RawMeasurements{IOD: 1})
+	assert.EqualError(t, ow.Err(), "write failed")
+}
+
+func TestSignalStrength(t *testing.T) {
+	assert.Equal(t, 1, signalStrength(0))
+	assert.Equal(t, 1, signalStrength(11))
+	assert.Equal(t, 2, signalStrength(12))
+	assert.Equal(t, 7, signalStrength(45))
+	assert.Equal(t, 9, signalStrength(54))
+	assert.Equal(t, 9, signalStrength(60))
+}
+
+func TestCallbacks(t *testing.T) {
+	ow := NewObservationWriter(&bytes.Buffer{}, Header{})
+	nw := NewNavigationWriter(&bytes.Buffer{}, Header{})
+
+	cb := Callbacks(ow, nw)
+	assert.NotNil(t, cb.MeasurementTime)
+	assert.NotNil(t, cb.RawMeasurements)
+	assert.NotNil(t, cb.Subframe)
+	assert.Nil(t, cb.NavData)
+
+	cb.MeasurementTime(skytraq.MeasurementTime{IOD: 1, Week: 2200})
+	assert.Equal(t, 2200, nw.referenceWeek)
+	assert.Equal(t, 1, ow.pendingIOD)
+
+	cb = Callbacks(nil, nw)
+	assert.NotNil(t, cb.MeasurementTime)
+	assert.NotNil(t, cb.Subframe)
+}
@@ -0,0 +1,106 @@
+// Package rinex writes RINEX 3 observation and GPS navigation files from the raw measurement
+// output of a SkyTraq receiver.
+package rinex
+
+import (
+	"fmt"
+	"github.com/jd3nn1s/skytraq"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	version        = 3.04
+	defaultProgram = "skytraq"
+)
+
+// Header fields describing the receiver and station. Fields left empty are written as blanks.
+type Header struct {
+	Program         string // defaults to "skytraq"
+	RunBy           string
+	Date            time.Time // file creation time, defaults to the current time
+	MarkerName      string
+	Observer        string
+	Agency          string
+	ReceiverNumber  string
+	ReceiverType    string
+	ReceiverVersion string
+	AntennaNumber   string
+	AntennaType     string
+	ApproxPosition  skytraq.ECEF
+	AntennaDelta    skytraq.ENU // antenna height and eccentricities from the marker
+}
+
+// Callbacks for skytraq.Connection.Start that write the measurements and subframes received to the
+// writers. Either writer may be nil. Write errors are available from each writer's Err method.
+func Callbacks(obs *ObservationWriter, nav *NavigationWriter) skytraq.Callbacks {
+	cb := skytraq.Callbacks{}
+	if obs != nil {
+		cb.MeasurementTime = obs.MeasurementTime
+		cb.RawMeasurements = obs.RawMeasurements
+	}
+	if nav != nil {
+		cb.Subframe = nav.Subframe
+		cb.MeasurementTime = nav.MeasurementTime
+	}
+	if obs != nil && nav != nil {
+		cb.MeasurementTime = func(mt skytraq.MeasurementTime) {
+			obs.MeasurementTime(mt)
+			nav.MeasurementTime(mt)
+		}
+	}
+	return cb
+}
+
+// Writes header lines and records, keeping the first error so that callers need only check it
+// once.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) line(format string, args ...interface{}) {
+	if lw.err != nil {
+		return
+	}
+	_, lw.err = io.WriteString(lw.w, fmt.Sprintf(format, args...)+"\n")
+}
+
+// Write a header line with its label in columns 61 to 80.
+func (lw *lineWriter) header(label, format string, args ...interface{}) {
+	content := fmt.Sprintf(format, args...)
+	if len(content) > 60 {
+		content = content[:60]
+	}
+	lw.line("%-60s%s", content, label)
+}
+
+func (lw *lineWriter) programHeader(h Header) {
+	program := h.Program
+	if program == "" {
+		program = defaultProgram
+	}
+	lw.header("PGM / RUN BY / DATE", "%-20s%-20s%-20s", truncate(program, 20), truncate(h.RunBy, 20),
+		h.date().UTC().Format("20060102 150405")+" UTC")
+}
+
+func (h Header) date() time.Time {
+	if h.Date.IsZero() {
+		return time.Now()
+	}
+	return h.Date
+}
+
+func truncate(s string, length int) string {
+	s = strings.TrimSpace(s)
+	if len(s) > length {
+		return s[:length]
+	}
+	return s
+}
+
+// Satellite identifier for a GPS PRN, e.g. G05.
+func gpsSatellite(prn int) string {
+	return fmt.Sprintf("G%02d", prn)
+}
@@ -0,0 +1,181 @@
+package skytraq
+
+import "time"
+
+type Constellation int
+
+const (
+	ConstellationUnknown Constellation = iota
+	ConstellationGPS
+	ConstellationSBAS
+	ConstellationGLONASS
+	ConstellationGalileo
+	ConstellationBeiDou
+	ConstellationQZSS
+)
+
+func (c Constellation) String() string {
+	switch c {
+	case ConstellationGPS:
+		return "GPS"
+	case ConstellationSBAS:
+		return "SBAS"
+	case ConstellationGLONASS:
+		return "GLONASS"
+	case ConstellationGalileo:
+		return "Galileo"
+	case ConstellationBeiDou:
+		return "BeiDou"
+	case ConstellationQZSS:
+		return "QZSS"
+	}
+	return "Unknown"
+}
+
+// A satellite seen by the device.
+type SatelliteStatus struct {
+	PRN           int
+	Constellation Constellation
+	Elevation     int // degrees
+	Azimuth       int // degrees true
+	CN0           int // dB-Hz, 0 when not tracked
+	UsedInFix     bool
+}
+
+// Constellation of a satellite from its PRN as numbered by the device and NMEA 0183.
+func prnConstellation(prn int) Constellation {
+	switch {
+	case prn >= 1 && prn <= 32:
+		return ConstellationGPS
+	case prn >= 33 && prn <= 64, prn >= 120 && prn <= 158:
+		return ConstellationSBAS
+	case prn >= 65 && prn <= 96:
+		return ConstellationGLONASS
+	case prn >= 193 && prn <= 200:
+		return ConstellationQZSS
+	case prn >= 201 && prn <= 237:
+		return ConstellationBeiDou
+	}
+	return ConstellationUnknown
+}
+
+// Constellation of a satellite reported in an NMEA sentence. Combined (GN) and GPS talkers
+// also report other systems, so those fall back to the PRN.
+func talkerConstellation(talker string, prn int) Constellation {
+	switch talker {
+	case "GL":
+		return ConstellationGLONASS
+	case "GA":
+		return ConstellationGalileo
+	case "BD", "GB":
+		return ConstellationBeiDou
+	case "GQ", "QZ":
+		return ConstellationQZSS
+	}
+	return prnConstellation(prn)
+}
+
+// Satellites assigned to a channel of the device.
+func (cs SVChannelStatus) Satellites() []SatelliteStatus {
+	satellites := make([]SatelliteStatus, 0, len(cs.Channels))
+	for _, ch := range cs.Channels {
+		if ch.SVID == 0 {
+			continue
+		}
+		satellites = append(satellites, SatelliteStatus{
+			PRN:           ch.SVID,
+			Constellation: prnConstellation(ch.SVID),
+			Elevation:     ch.Elevation,
+			Azimuth:       ch.Azimuth,
+			CN0:           ch.CN0,
+			UsedInFix:     ch.Status&ChannelUsedInFix != 0,
+		})
+	}
+	return satellites
+}
+
+type satelliteID struct {
+	constellation Constellation
+	prn           int
+}
+
+// Builds the satellites of an epoch from NMEA sentences. Each talker reports its satellites
+// in view as a group of GSV sentences and the satellites used in the fix in GSA, in either order.
+// Epochs are separated by the sentences carrying the time of the fix, GGA, RMC and ZDA, which the
+// device outputs at the start of each epoch: an epoch ends when one of them reports a different
+// time, or is repeated when the time is not yet known.
+type satelliteTracker struct {
+	time      time.Duration // of the fix in the current epoch
+	timeKnown bool
+	seen      map[string]bool // sentence types carrying the time seen in the current epoch
+
+	used     map[satelliteID]bool
+	inView   []SatelliteStatus
+	group    []GSVSatellite
+	complete bool // at least one GSV group has been received in the current epoch
+}
+
+// Note a sentence carrying the time of the fix, returning the satellites of the previous epoch
+// when it starts a new one.
+func (st *satelliteTracker) fix(sentenceType string, timeOfDay time.Duration, known bool) ([]SatelliteStatus, bool) {
+	var satellites []SatelliteStatus
+	ok := false
+	if st.seen[sentenceType] || (known && st.timeKnown && timeOfDay != st.time) {
+		satellites, ok = st.epoch()
+	}
+	if st.seen == nil {
+		st.seen = map[string]bool{}
+	}
+	st.seen[sentenceType] = true
+	if known {
+		st.time = timeOfDay
+		st.timeKnown = true
+	}
+	return satellites, ok
+}
+
+func (st *satelliteTracker) gsa(talker string, gsa GSA) {
+	if st.used == nil {
+		st.used = map[satelliteID]bool{}
+	}
+	for _, prn := range gsa.PRNs {
+		st.used[satelliteID{talkerConstellation(talker, prn), prn}] = true
+	}
+}
+
+func (st *satelliteTracker) gsv(gsv GSV) {
+	if gsv.SentenceNumber == 1 {
+		st.group = st.group[:0]
+	}
+	st.group = append(st.group, gsv.Satellites...)
+	if gsv.SentenceNumber != gsv.SentenceCount {
+		return
+	}
+
+	for _, sat := range st.group {
+		st.inView = append(st.inView, SatelliteStatus{
+			PRN:           sat.PRN,
+			Constellation: talkerConstellation(gsv.Talker, sat.PRN),
+			Elevation:     sat.Elevation,
+			Azimuth:       sat.Azimuth,
+			CN0:           sat.SNR,
+		})
+	}
+	st.group = st.group[:0]
+	st.complete = true
+}
+
+// Returns the satellites of the epoch, if any GSV group was received, and starts the next.
+func (st *satelliteTracker) epoch() ([]SatelliteStatus, bool) {
+	complete := st.complete
+	satellites := append([]SatelliteStatus{}, st.inView...)
+	for i := range satellites {
+		satellites[i].UsedInFix = st.used[satelliteID{satellites[i].Constellation, satellites[i].PRN}]
+	}
+	st.seen = nil
+	st.used = nil
+	st.inView = nil
+	st.group = st.group[:0]
+	st.complete = false
+	return satellites, complete
+}
@@ -0,0 +1,135 @@
+package skytraq
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestConstellation(t *testing.T) {
+	tests := []struct {
+		talker   string
+		prn      int
+		expected Constellation
+	}{
+		{"GP", 5, ConstellationGPS},
+		{"GP", 46, ConstellationSBAS},
+		{"GN", 70, ConstellationGLONASS},
+		{"GL", 2, ConstellationGLONASS},
+		{"BD", 5, ConstellationBeiDou},
+		{"GA", 11, ConstellationGalileo},
+		{"GN", 133, ConstellationSBAS},
+		{"GN", 193, ConstellationQZSS},
+		{"GN", 210, ConstellationBeiDou},
+		{"GN", 0, ConstellationUnknown},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, talkerConstellation(test.talker, test.prn), "%v %v", test.talker, test.prn)
+	}
+	assert.Equal(t, "GLONASS", ConstellationGLONASS.String())
+	assert.Equal(t, "Unknown", Constellation(42).String())
+}
+
+func TestSVChannelStatusSatellites(t *testing.T) {
+	cs := SVChannelStatus{
+		IOD: 7,
+		Channels: []ChannelStatus{
+			{Channel: 0, SVID: 3, CN0: 45, Elevation: 67, Azimuth: 296, Status: ChannelBitSync | ChannelUsedInFix},
+			{Channel: 1},
+			{Channel: 2, SVID: 70, CN0: 20, Elevation: -2, Azimuth: 10, Status: ChannelPullInDone},
+		},
+	}
+	assert.Equal(t, []SatelliteStatus{
+		{PRN: 3, Constellation: ConstellationGPS, Elevation: 67, Azimuth: 296, CN0: 45, UsedInFix: true},
+		{PRN: 70, Constellation: ConstellationGLONASS, Elevation: -2, Azimuth: 10, CN0: 20},
+	}, cs.Satellites())
+}
+
+func TestStartSatelliteStatusBinary(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseSVChannelStatus, svChannelStatusData(SVChannelStatus{
+		IOD:      1,
+		Channels: []ChannelStatus{{SVID: 12, CN0: 38, Elevation: 45, Azimuth: 90, Status: ChannelUsedInFix}},
+	}), 0))
+
+	var satellites []SatelliteStatus
+	err := c.Start(context.Background(), Callbacks{
+		SatelliteStatus: func(s []SatelliteStatus) {
+			satellites = s
+		},
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, []SatelliteStatus{
+		{PRN: 12, Constellation: ConstellationGPS, Elevation: 45, Azimuth: 90, CN0: 38, UsedInFix: true},
+	}, satellites)
+}
+
+func startSatelliteStatus(t *testing.T, sentences ...string) [][]SatelliteStatus {
+	c, m := connection()
+	for _, s := range sentences {
+		m.ReadBuf.WriteString(nmeaSentence(s))
+	}
+
+	var epochs [][]SatelliteStatus
+	err := c.Start(context.Background(), Callbacks{
+		SatelliteStatus: func(s []SatelliteStatus) {
+			epochs = append(epochs, s)
+		},
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	return epochs
+}
+
+func TestStartSatelliteStatusNMEA(t *testing.T) {
+	epochs := startSatelliteStatus(t,
+		"GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,",
+		"GNGSA,A,3,04,05,,,,,,,,,,,2.5,1.3,2.1",
+		"GNGSA,A,3,70,,,,,,,,,,,,2.5,1.3,2.1",
+		"GPGSV,2,1,05,04,40,083,46,05,17,308,41,12,07,344,39,14,22,228,",
+		"GPGSV,2,2,05,46,33,210,30",
+		"GLGSV,1,1,01,70,10,020,35",
+		"GPRMC,123519,A,4807.038,N,01131.000,E,000.5,054.7,191124,020.3,E",
+		// satellites used in the fix reported after the satellites in view
+		"GPGGA,123520,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,",
+		"GPGSV,1,1,02,04,40,083,46,05,17,308,41",
+		"GPRMC,123520,A,4807.038,N,01131.000,E,000.5,054.7,191124,020.3,E",
+		"GPGSA,A,3,05,,,,,,,,,,,,2.5,1.3,2.1",
+		"GPVTG,054.7,T,034.4,M,005.5,N,010.2,K,A",
+		// the last epoch is not complete until the next starts
+		"GPGGA,123521,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,",
+		"GPGSV,1,1,00",
+	)
+	assert.Equal(t, [][]SatelliteStatus{
+		{
+			{PRN: 4, Constellation: ConstellationGPS, Elevation: 40, Azimuth: 83, CN0: 46, UsedInFix: true},
+			{PRN: 5, Constellation: ConstellationGPS, Elevation: 17, Azimuth: 308, CN0: 41, UsedInFix: true},
+			{PRN: 12, Constellation: ConstellationGPS, Elevation: 7, Azimuth: 344, CN0: 39},
+			{PRN: 14, Constellation: ConstellationGPS, Elevation: 22, Azimuth: 228},
+			{PRN: 46, Constellation: ConstellationSBAS, Elevation: 33, Azimuth: 210, CN0: 30},
+			{PRN: 70, Constellation: ConstellationGLONASS, Elevation: 10, Azimuth: 20, CN0: 35, UsedInFix: true},
+		},
+		{
+			{PRN: 4, Constellation: ConstellationGPS, Elevation: 40, Azimuth: 83, CN0: 46},
+			{PRN: 5, Constellation: ConstellationGPS, Elevation: 17, Azimuth: 308, CN0: 41, UsedInFix: true},
+		},
+	}, epochs)
+}
+
+func TestStartSatelliteStatusNMEANoTime(t *testing.T) {
+	// before the device knows the time, a repeated GGA starts the next epoch
+	epochs := startSatelliteStatus(t,
+		"GPGGA,,,,,,0,00,,,M,,M,,",
+		"GPGSV,1,1,01,14,25,170,00",
+		"GPGGA,,,,,,0,00,,,M,,M,,",
+		"GPGSV,1,1,00",
+		"GPGGA,,,,,,0,00,,,M,,M,,",
+		// no GSV in this epoch
+		"GPGGA,,,,,,0,00,,,M,,M,,",
+	)
+	assert.Equal(t, [][]SatelliteStatus{
+		{{PRN: 14, Constellation: ConstellationGPS, Elevation: 25, Azimuth: 170}},
+		{},
+	}, epochs)
+}
@@ -9,33 +9,45 @@ import (
 type Callbacks struct {
 	SoftwareVersion func(SoftwareVersion)
 	NavData         func(NavData)
+	SoftwareCRC     func(SoftwareCRC)
+	PositionRate    func(PositionRate)
+	PowerMode       func(PowerMode)
+
+	// raw measurements
+	MeasurementTime  func(MeasurementTime)
+	RawMeasurements  func(RawMeasurements)
+	SVChannelStatus  func(SVChannelStatus)
+	ReceiverNavState func(ReceiverNavState)
+	Subframe         func(Subframe)
+
+	// satellites seen each epoch, from SV channel status or NMEA GSV and GSA sentences. NMEA epochs
+	// are delivered when the GGA, RMC or ZDA of the next epoch is received.
+	SatelliteStatus func([]SatelliteStatus)
+
+	// NMEA sentences
+	GGA func(GGA)
+	RMC func(RMC)
+	GSA func(GSA)
+	GSV func(GSV)
+	VTG func(VTG)
+	ZDA func(ZDA)
 }
 
 func (c *Connection) Start(ctx context.Context, cb Callbacks) error {
 	for {
-		f, err := c.ReadFrame()
+		f, s, err := c.readMessage()
 		if err != nil {
 			return err
 		}
 
-		// successfully read frame, dispatch it
-		switch f.ID {
-		case ResponseSoftwareVersion:
-			if cb.SoftwareVersion != nil {
-				version, err := f.softwareVersion()
-				if err != nil {
-					return errors.Wrapf(err, "error when converting to SoftwareVersion structure")
-				}
-				cb.SoftwareVersion(version)
-			}
-		case ResponseNavData:
-			if cb.NavData != nil {
-				navData, err := f.navData()
-				if err != nil {
-					return errors.Wrapf(err, "error when converting to NavData structure")
-				}
-				cb.NavData(navData)
-			}
+		// successfully read frame or sentence, dispatch it
+		if f != nil {
+			err = c.dispatchFrame(f, cb)
+		} else {
+			err = c.dispatchSentence(s, cb)
+		}
+		if err != nil {
+			return err
 		}
 
 		select {
@@ -46,3 +58,190 @@ func (c *Connection) Start(ctx context.Context, cb Callbacks) error {
 		}
 	}
 }
+
+func (c *Connection) dispatchFrame(f *Frame, cb Callbacks) error {
+	switch f.ID {
+	case ResponseSoftwareVersion:
+		if cb.SoftwareVersion != nil {
+			version, err := f.softwareVersion()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to SoftwareVersion structure")
+			}
+			cb.SoftwareVersion(version)
+		}
+	case ResponseNavData:
+		if cb.NavData != nil {
+			navData, err := f.navData()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to NavData structure")
+			}
+			if c.leapSecondsValid {
+				navData.Time.LeapSeconds = c.leapSeconds
+			}
+			cb.NavData(navData)
+		}
+	case ResponseSoftwareCRC:
+		if cb.SoftwareCRC != nil {
+			crc, err := f.softwareCRC()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to SoftwareCRC")
+			}
+			cb.SoftwareCRC(crc)
+		}
+	case ResponsePositionRate:
+		if cb.PositionRate != nil {
+			rate, err := f.positionRate()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to PositionRate")
+			}
+			cb.PositionRate(rate)
+		}
+	case ResponsePowerMode:
+		if cb.PowerMode != nil {
+			mode, err := f.powerMode()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to PowerMode")
+			}
+			cb.PowerMode(mode)
+		}
+	case ResponseMeasurementTime:
+		if cb.MeasurementTime != nil {
+			mt, err := f.measurementTime()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to MeasurementTime structure")
+			}
+			if c.leapSecondsValid {
+				mt.LeapSeconds = c.leapSeconds
+			}
+			cb.MeasurementTime(mt)
+		}
+	case ResponseRawMeasurements:
+		if cb.RawMeasurements != nil {
+			rm, err := f.rawMeasurements()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to RawMeasurements structure")
+			}
+			cb.RawMeasurements(rm)
+		}
+	case ResponseSVChannelStatus:
+		if cb.SVChannelStatus != nil || cb.SatelliteStatus != nil {
+			cs, err := f.svChannelStatus()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to SVChannelStatus structure")
+			}
+			if cb.SVChannelStatus != nil {
+				cb.SVChannelStatus(cs)
+			}
+			if cb.SatelliteStatus != nil {
+				cb.SatelliteStatus(cs.Satellites())
+			}
+		}
+	case ResponseReceiverNavState:
+		if cb.ReceiverNavState != nil {
+			ns, err := f.receiverNavState()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to ReceiverNavState structure")
+			}
+			cb.ReceiverNavState(ns)
+		}
+	case ResponseSubframe:
+		if cb.Subframe != nil {
+			sf, err := f.subframe()
+			if err != nil {
+				return errors.Wrapf(err, "error when converting to Subframe structure")
+			}
+			cb.Subframe(sf)
+		}
+	}
+	return nil
+}
+
+func (c *Connection) dispatchSentence(s *Sentence, cb Callbacks) error {
+	if cb.SatelliteStatus != nil {
+		if err := c.trackSatellites(s, cb); err != nil {
+			return err
+		}
+	}
+
+	switch s.Type {
+	case "GGA":
+		if cb.GGA != nil {
+			gga, err := s.gga()
+			if err != nil {
+				return err
+			}
+			cb.GGA(gga)
+		}
+	case "RMC":
+		if cb.RMC != nil {
+			rmc, err := s.rmc()
+			if err != nil {
+				return err
+			}
+			cb.RMC(rmc)
+		}
+	case "GSA":
+		if cb.GSA != nil {
+			gsa, err := s.gsa()
+			if err != nil {
+				return err
+			}
+			cb.GSA(gsa)
+		}
+	case "GSV":
+		if cb.GSV != nil {
+			gsv, err := s.gsv()
+			if err != nil {
+				return err
+			}
+			cb.GSV(gsv)
+		}
+	case "VTG":
+		if cb.VTG != nil {
+			vtg, err := s.vtg()
+			if err != nil {
+				return err
+			}
+			cb.VTG(vtg)
+		}
+	case "ZDA":
+		if cb.ZDA != nil {
+			zda, err := s.zda()
+			if err != nil {
+				return err
+			}
+			cb.ZDA(zda)
+		}
+	}
+	return nil
+}
+
+func (c *Connection) trackSatellites(s *Sentence, cb Callbacks) error {
+	switch s.Type {
+	case "GGA", "RMC", "ZDA":
+		if len(s.Fields) == 0 {
+			return nil
+		}
+		p := fieldParser{}
+		timeOfDay := p.timeOfDay(s.Fields[0])
+		if p.err != nil {
+			return errors.Wrapf(p.err, "unable to parse %v", s.Type)
+		}
+		if satellites, ok := c.satellites.fix(s.Type, timeOfDay, s.Fields[0] != ""); ok {
+			cb.SatelliteStatus(satellites)
+		}
+	case "GSA":
+		gsa, err := s.gsa()
+		if err != nil {
+			return err
+		}
+		c.satellites.gsa(s.Talker, gsa)
+	case "GSV":
+		gsv, err := s.gsv()
+		if err != nil {
+			return err
+		}
+		c.satellites.gsv(gsv)
+	}
+	return nil
+}
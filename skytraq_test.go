@@ -32,3 +32,43 @@ func TestStart(t *testing.T) {
 	assert.True(t, cbResult.SoftwareVersion)
 	assert.True(t, cbResult.NavData)
 }
+
+func TestStartConfigResponses(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseSoftwareCRC, []byte{1, 0xab, 0xcd}, 0))
+	m.ReadBuf.Write(frameData(ResponsePositionRate, []byte{5}, 0))
+	m.ReadBuf.Write(frameData(ResponsePowerMode, []byte{0}, 0))
+
+	var crc SoftwareCRC
+	var rate PositionRate
+	mode := PowerSave
+	err := c.Start(context.Background(), Callbacks{
+		SoftwareCRC: func(v SoftwareCRC) {
+			crc = v
+		},
+		PositionRate: func(v PositionRate) {
+			rate = v
+		},
+		PowerMode: func(v PowerMode) {
+			mode = v
+		},
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, SoftwareCRC(0xabcd), crc)
+	assert.Equal(t, "ABCD", crc.String())
+	assert.Equal(t, PositionRate(5), rate)
+	assert.Equal(t, PowerModeNormal, mode)
+}
+
+func TestStartShortSoftwareCRC(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseSoftwareCRC, []byte{1, 0xab}, 0))
+
+	err := c.Start(context.Background(), Callbacks{
+		SoftwareCRC: func(v SoftwareCRC) {
+			t.Fail()
+		},
+	})
+	assert.EqualError(t, err,
+		"error when converting to SoftwareCRC: softwareCRC conversion requires 3 bytes but received 2")
+}
@@ -0,0 +1,108 @@
+package skytraq
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+// time between software version queries while waiting for the device to reboot
+var rebootPollInterval = time.Second
+
+const (
+	// range of initial altitudes, in meters, accepted by the device
+	minRestartAltitude = -1000
+	maxRestartAltitude = 18300
+
+	// the only type of factory reset, which reboots the device once the defaults are restored
+	factoryResetReboot = 1
+)
+
+// How much of the device's stored state is used when it restarts.
+type RestartMode uint8
+
+const (
+	RestartHot  RestartMode = 1
+	RestartWarm RestartMode = 2
+	RestartCold RestartMode = 3
+)
+
+func restartData(mode RestartMode, position LLA, utc time.Time) ([]byte, error) {
+	if math.Abs(position.Latitude) > 90 || math.Abs(position.Longitude) > 180 {
+		return nil, errors.Errorf("invalid initial position %v, %v", position.Latitude, position.Longitude)
+	}
+	if position.Altitude < minRestartAltitude || position.Altitude > maxRestartAltitude {
+		return nil, errors.Errorf("initial altitude %v m is outside of the range %v to %v m", position.Altitude,
+			minRestartAltitude, maxRestartAltitude)
+	}
+
+	utc = utc.UTC()
+	data := make([]byte, 14)
+	data[0] = byte(mode)
+	binary.BigEndian.PutUint16(data[1:3], uint16(utc.Year()))
+	data[3] = byte(utc.Month())
+	data[4] = byte(utc.Day())
+	data[5] = byte(utc.Hour())
+	data[6] = byte(utc.Minute())
+	data[7] = byte(utc.Second())
+	binary.BigEndian.PutUint16(data[8:10], uint16(int16(math.Round(position.Latitude*100))))
+	binary.BigEndian.PutUint16(data[10:12], uint16(int16(math.Round(position.Longitude*100))))
+	binary.BigEndian.PutUint16(data[12:14], uint16(int16(math.Round(position.Altitude))))
+	return data, nil
+}
+
+// Restart the device, seeding it with an approximate position and the current UTC time. The
+// position is sent to a hundredth of a degree and the altitude to the nearest meter.
+func (c *Connection) Restart(ctx context.Context, mode RestartMode, initialPosition LLA, utcTime time.Time) error {
+	data, err := restartData(mode, initialPosition, utcTime)
+	if err != nil {
+		return err
+	}
+	if err := c.configure(ctx, &Frame{
+		ID:   CommandSystemRestart,
+		Data: data,
+	}); err != nil {
+		return errors.Wrapf(err, "unable to restart")
+	}
+	return nil
+}
+
+// Reset the device's configuration to factory defaults, after which it reboots. When wait is true
+// the software version is queried until the device responds or ctx is done.
+//
+// The factory default baud rate may differ from the rate in use, in which case the device will not
+// respond at the current rate after rebooting.
+func (c *Connection) FactoryReset(ctx context.Context, wait bool) error {
+	if err := c.configure(ctx, &Frame{
+		ID:   CommandSetFactoryDefaults,
+		Data: []byte{factoryResetReboot},
+	}); err != nil {
+		return errors.Wrapf(err, "unable to reset to factory defaults")
+	}
+	// the device boots outputting NMEA with its default sentences
+	c.messageType = MessageTypeNMEA
+	c.nmeaIntervals = defaultNMEAIntervals
+	if !wait {
+		return nil
+	}
+
+	for {
+		_, err := c.QuerySoftwareVersion(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return errors.Wrapf(ctxErr, "device did not respond after reboot: %v", err)
+		}
+		logrus.Debugf("waiting for device to reboot: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "device did not respond after reboot: %v", err)
+		case <-time.After(rebootPollInterval):
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package skytraq
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRestart(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandSystemRestart)}, 0))
+
+	utc := time.Date(2022, time.March, 7, 11, 59, 43, 500000000, time.UTC)
+	assert.NoError(t, c.Restart(context.Background(), RestartWarm, LLA{-33.8688, 151.2093, 46.5}, utc))
+	assert.Equal(t, frameData(CommandSystemRestart, []byte{
+		2,
+		0x07, 0xe6, 3, 7, 11, 59, 43,
+		0xf2, 0xc5, // -3387
+		0x3b, 0x11, // 15121
+		0x00, 0x2f, // 47
+	}, 0), m.WriteBuf.Bytes())
+}
+
+func TestRestartLocalTime(t *testing.T) {
+	data, err := restartData(RestartCold, LLA{}, time.Date(2022, time.March, 7, 23, 30, 0, 0,
+		time.FixedZone("UTC-5", -5*60*60)))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{3, 0x07, 0xe6, 3, 8, 4, 30, 0, 0, 0, 0, 0, 0, 0}, data)
+}
+
+func TestRestartInvalidPosition(t *testing.T) {
+	c, m := connection()
+	utc := time.Now()
+	assert.Error(t, c.Restart(context.Background(), RestartHot, LLA{Latitude: 91}, utc))
+	assert.Error(t, c.Restart(context.Background(), RestartHot, LLA{Longitude: -180.5}, utc))
+	assert.Error(t, c.Restart(context.Background(), RestartHot, LLA{Altitude: 40000}, utc))
+	assert.Error(t, c.Restart(context.Background(), RestartHot, LLA{Altitude: 18301}, utc))
+	assert.Error(t, c.Restart(context.Background(), RestartHot, LLA{Altitude: -1000.5}, utc))
+	assert.Error(t, c.Restart(context.Background(), RestartHot, LLA{Altitude: -30000}, utc))
+	assert.Equal(t, 0, m.WriteBuf.Len())
+}
+
+func TestRestartAltitudeLimits(t *testing.T) {
+	_, err := restartData(RestartHot, LLA{Altitude: -1000}, time.Now())
+	assert.NoError(t, err)
+	_, err = restartData(RestartHot, LLA{Altitude: 18300}, time.Now())
+	assert.NoError(t, err)
+}
+
+func TestFactoryReset(t *testing.T) {
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandSetFactoryDefaults)}, 0))
+
+	c.messageType = MessageTypeBinary
+	c.nmeaIntervals = NMEAIntervals{GGA: 1}
+
+	assert.NoError(t, c.FactoryReset(context.Background(), false))
+	assert.Equal(t, frameData(CommandSetFactoryDefaults, []byte{1}, 0), m.WriteBuf.Bytes())
+	assert.Equal(t, MessageTypeNMEA, c.messageType)
+	assert.Equal(t, defaultNMEAIntervals, c.nmeaIntervals)
+}
+
+func TestFactoryResetReboot(t *testing.T) {
+	oldInterval := rebootPollInterval
+	defer func() {
+		rebootPollInterval = oldInterval
+	}()
+	rebootPollInterval = 0
+
+	c, m := connection()
+	c.writeRetries = 1
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandSetFactoryDefaults)}, 0))
+	// first query is rejected while the device is starting
+	m.ReadBuf.Write(frameData(ResponseNACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandQuerySoftwareVersion)}, 0))
+	m.ReadBuf.Write(frameData(ResponseSoftwareVersion, versionData, 0))
+
+	assert.NoError(t, c.FactoryReset(context.Background(), true))
+	query := frameData(CommandQuerySoftwareVersion, []byte{1}, 0)
+	expected := append(frameData(CommandSetFactoryDefaults, []byte{1}, 0), query...)
+	assert.Equal(t, append(expected, query...), m.WriteBuf.Bytes())
+}
+
+func TestFactoryResetRebootTimeout(t *testing.T) {
+	oldInterval := rebootPollInterval
+	defer func() {
+		rebootPollInterval = oldInterval
+	}()
+	rebootPollInterval = 10 * time.Millisecond
+
+	c, m := connection()
+	m.ReadBuf.Write(frameData(ResponseACK, []byte{byte(CommandSetFactoryDefaults)}, 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.FactoryReset(ctx, true)
+	assert.Error(t, err)
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+}
+
+func TestFactoryResetRejected(t *testing.T) {
+	c, m := connection()
+	c.writeRetries = 1
+	c.messageType = MessageTypeBinary
+	m.ReadBuf.Write(frameData(ResponseNACK, []byte{byte(CommandSetFactoryDefaults)}, 0))
+
+	assert.Error(t, c.FactoryReset(context.Background(), false))
+	assert.Equal(t, MessageTypeBinary, c.messageType)
+}